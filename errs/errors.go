@@ -8,8 +8,23 @@
 
 package errs
 
+import "fmt"
+
+// Snippet keeps the beginning of the body of an erroneous HTTP response,
+// which often explains the failure, e.g. an API error message or a block page.
+// It is embedded into errors returned for HTTP error statuses.
+type Snippet struct {
+	Body []byte
+}
+
+// BodySnippet returns the beginning of the erroneous response body if it was captured.
+func (s Snippet) BodySnippet() []byte {
+	return s.Body
+}
+
 // BadRequest 400 The server cannot or will not process the request due to an apparent client error (e.g., malformed request syntax, size too large, invalid request message framing, or deceptive request routing).
 type BadRequest struct {
+	Snippet
 	Err error
 }
 
@@ -24,12 +39,14 @@ func (e *BadRequest) Error() string {
 //
 // Client does not have access rights to the content.
 type Unauthorized struct {
+	Snippet
 }
 
 func (e *Unauthorized) Error() string { return "401 Unauthorized" }
 
 // ProxyAuthenticationRequired : Proxy Authentication Required 407
 type ProxyAuthenticationRequired struct {
+	Snippet
 }
 
 func (e *ProxyAuthenticationRequired) Error() string { return "407 Proxy Authentication Required" }
@@ -47,15 +64,46 @@ func (e *ForbiddenByRobots) Error() string { return "403 Forbidden by robots.txt
 //
 // Client does not have access rights to the content so server is rejecting to give proper response.
 type Forbidden struct {
+	Snippet
 	URL string
 }
 
 func (e *Forbidden) Error() string { return "403 Forbidden: " + e.URL }
 
+// ForbiddenHost 403
+//
+// Fetching of the host is not allowed by the fetcher configuration.
+type ForbiddenHost struct {
+	Host string
+}
+
+func (e *ForbiddenHost) Error() string { return "403 Forbidden host: " + e.Host }
+
+// ForbiddenRedirect 403
+//
+// Fetcher is not allowed to follow a redirect to URL, e.g. because it leads to another origin.
+type ForbiddenRedirect struct {
+	URL string
+}
+
+func (e *ForbiddenRedirect) Error() string { return "403 Forbidden redirect to: " + e.URL }
+
+// Blocked 403
+//
+// Server answered with success status but the body is a block page, e.g. an anti-bot challenge, a captcha or a soft 404.
+type Blocked struct {
+	URL string
+	// Pattern is the block pattern matched by the body.
+	Pattern string
+}
+
+func (e *Blocked) Error() string { return "403 Blocked page from: " + e.URL + " matching " + e.Pattern }
+
 // NotFound 404
 //
 // Server can not find requested resource. This response code probably is most famous one due to its frequency to occur in web.
 type NotFound struct {
+	Snippet
 	URL string
 }
 
@@ -63,19 +111,42 @@ func (e *NotFound) Error() string {
 	return "404 Not found: " + e.URL
 }
 
+// TooManyRequests 429
+//
+// Request was not sent because the rate limit would not allow it before its deadline.
+type TooManyRequests struct {
+	URL string
+}
+
+func (e *TooManyRequests) Error() string { return "429 Too many requests, rate limit exceeded for: " + e.URL }
+
 // InternalServerError 500
 // A generic error message, given when an unexpected condition was encountered and no more specific message is suitable
 type InternalServerError struct {
+	Snippet
 }
 
 func (*InternalServerError) Error() string {
 	return "500 Internal Server Error"
 }
 
+// ServiceUnavailable 503
+//
+// Server answered with success status but the body signals a transient error, e.g. "service temporarily unavailable, try again".
+type ServiceUnavailable struct {
+	Snippet
+	URL string
+}
+
+func (e *ServiceUnavailable) Error() string {
+	return "503 Service temporarily unavailable: " + e.URL
+}
+
 // BadGateway 502
 //
 // This error response means that the server, while working as a gateway to get a response needed to handle the request, got an invalid response.
 type BadGateway struct {
+	Snippet
 	What string
 }
 
@@ -83,16 +154,125 @@ func (e *BadGateway) Error() string {
 	return "502 Invalid " + e.What + " from server"
 }
 
+// BadRedirect 502
+//
+// Server answered with a redirect status but without a Location header to follow.
+type BadRedirect struct {
+	Snippet
+	URL    string
+	Status int
+}
+
+func (e *BadRedirect) Error() string {
+	return fmt.Sprintf("502 Redirect %d without Location from: %s", e.Status, e.URL)
+}
+
+// EmptyResponse 502
+//
+// Server answered with success status but the response body is empty or too short to be a real document.
+// It often indicates a soft block or a page requiring JavaScript.
+type EmptyResponse struct {
+	URL string
+}
+
+func (e *EmptyResponse) Error() string {
+	return "502 Empty response from server: " + e.URL
+}
+
+// IncompleteRead 502
+//
+// Connection was dropped or compressed stream was truncated before the whole response body was received.
+// Content read before the error is still usable.
+type IncompleteRead struct {
+	URL string
+}
+
+func (e *IncompleteRead) Error() string {
+	return "502 Incomplete response body from server: " + e.URL
+}
+
+// InvalidJSON 502
+//
+// Response body expected to be a JSON document is not valid JSON or has a different content type.
+type InvalidJSON struct {
+	Snippet
+	URL string
+	Err error
+}
+
+func (e *InvalidJSON) Error() string {
+	return "502 Invalid JSON from: " + e.URL + ": " + e.Err.Error()
+}
+
+// BodyTooLarge 502
+//
+// Response body exceeds the maximum size the fetcher is configured to read. Content read before the limit is still usable.
+type BodyTooLarge struct {
+	URL   string
+	Limit int64
+}
+
+func (e *BodyTooLarge) Error() string {
+	return fmt.Sprintf("502 Response body exceeds %d bytes: %s", e.Limit, e.URL)
+}
+
+// UnsupportedContentType 502
+//
+// Response has a content type the fetcher is configured to treat as an error, e.g. a JSON error payload instead of a page.
+type UnsupportedContentType struct {
+	URL         string
+	ContentType string
+}
+
+func (e *UnsupportedContentType) Error() string {
+	return "502 Unsupported content type " + e.ContentType + ": " + e.URL
+}
+
+// BadDocument 502
+//
+// Response body was fetched successfully but cannot be parsed as HTML document.
+type BadDocument struct {
+	URL string
+	Err error
+}
+
+func (e *BadDocument) Error() string {
+	return "502 Cannot parse document " + e.URL + ": " + e.Err.Error()
+}
+
+// Canceled
+//
+// Request was canceled by the caller before it completed.
+type Canceled struct {
+	URL string
+}
+
+func (e *Canceled) Error() string {
+	return "Request canceled: " + e.URL
+}
+
 // GatewayTimeout Gateway Time-out 504
 //
 // This error response is given when the server is acting as a gateway and cannot get a response in time.
 type GatewayTimeout struct {
+	Snippet
 }
 
 func (e *GatewayTimeout) Error() string {
 	return "504 Timeout exceeded rendering page"
 }
 
+// ConnectionTimeout 504
+//
+// Connection to the server could not be established in time, i.e. dialing or the TLS handshake timed out.
+type ConnectionTimeout struct {
+	URL string
+}
+
+func (e *ConnectionTimeout) Error() string {
+	return "504 Connection timed out: " + e.URL
+}
+
 //Parser Errors generated by Dataflow kit Parser service
 //
 //ParserError returned if web page cannot be parsed correctly due to wrong payload structure
@@ -133,6 +313,7 @@ func (e *ErrStorageResult) Error() string {
 
 // Error represents all the rest (unspecified errors).
 type Error struct {
+	Snippet
 	Err string
 }
 
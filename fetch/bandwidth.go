@@ -0,0 +1,113 @@
+package fetch
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// byteBucket is a token bucket over bytes refilled at rate bytes per
+// second. It starts empty and holds at most a tenth of a second worth of
+// bytes, so reads after an idle period don't burst over the rate.
+type byteBucket struct {
+	mu     sync.Mutex
+	rate   int64
+	tokens float64
+	last   time.Time
+}
+
+// take takes n bytes from the bucket, waiting until they are refilled or
+// ctx is done. The bucket may go into debt, a large read makes the next
+// ones wait.
+func (b *byteBucket) take(ctx context.Context, n int) error {
+	b.mu.Lock()
+	now := time.Now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * float64(b.rate)
+		if max := float64(b.rate) / 10; b.tokens > max {
+			b.tokens = max
+		}
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	wait := time.Duration(-b.tokens / float64(b.rate) * float64(time.Second))
+	b.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// bandwidthLimiter holds the byte buckets of a BaseFetcher. The zero value
+// is ready to use.
+type bandwidthLimiter struct {
+	mu     sync.Mutex
+	global *byteBucket
+	hosts  map[string]*byteBucket
+}
+
+// buckets returns the buckets the bytes received from host are taken from.
+// The rates are fixed by the first call.
+func (l *bandwidthLimiter) buckets(host string, rate, hostRate int64) []*byteBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var buckets []*byteBucket
+	if rate > 0 {
+		if l.global == nil {
+			l.global = &byteBucket{rate: rate}
+		}
+		buckets = append(buckets, l.global)
+	}
+	if hostRate > 0 {
+		if l.hosts == nil {
+			l.hosts = make(map[string]*byteBucket)
+		}
+		bucket, ok := l.hosts[host]
+		if !ok {
+			bucket = &byteBucket{rate: hostRate}
+			l.hosts[host] = bucket
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// throttledReader delays reading of a body so the bytes read don't exceed
+// the rates of its buckets.
+type throttledReader struct {
+	io.ReadCloser
+	ctx     context.Context
+	buckets []*byteBucket
+}
+
+func (r throttledReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	for _, b := range r.buckets {
+		if waitErr := b.take(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// throttle limits reading of the body of resp to MaxBytesPerSecond and
+// MaxBytesPerSecondPerHost.
+func (bf *BaseFetcher) throttle(resp *http.Response) {
+	if bf.MaxBytesPerSecond <= 0 && bf.MaxBytesPerSecondPerHost <= 0 {
+		return
+	}
+	resp.Body = throttledReader{
+		ReadCloser: resp.Body,
+		ctx:        resp.Request.Context(),
+		buckets:    bf.bandwidth.buckets(resp.Request.URL.Host, bf.MaxBytesPerSecond, bf.MaxBytesPerSecondPerHost),
+	}
+}
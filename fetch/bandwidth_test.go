@@ -0,0 +1,55 @@
+package fetch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_MaxBytesPerSecond(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("a"), 30000))
+	}))
+	defer ts.Close()
+
+	read := func(fetcher *BaseFetcher) {
+		content, err := fetcher.Fetch(Request{URL: ts.URL})
+		if assert.NoError(t, err) {
+			data, err := ioutil.ReadAll(content)
+			assert.NoError(t, err)
+			assert.Len(t, data, 30000)
+			content.Close()
+		}
+	}
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.MaxBytesPerSecond = 100000
+	start := time.Now()
+	read(fetcher)
+	took := time.Since(start)
+	assert.True(t, took >= 300*time.Millisecond, "30 KB at 100 KB/s took %s", took)
+	assert.True(t, took < time.Second, "30 KB at 100 KB/s took %s", took)
+
+	//concurrent fetches from a host share its cap
+	fetcher, err = NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.MaxBytesPerSecondPerHost = 200000
+	start = time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read(fetcher)
+		}()
+	}
+	wg.Wait()
+	took = time.Since(start)
+	assert.True(t, took >= 300*time.Millisecond, "2x30 KB at 200 KB/s took %s", took)
+}
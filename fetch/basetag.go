@@ -0,0 +1,72 @@
+package fetch
+
+import (
+	"bytes"
+	"html"
+	"mime"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// isHTML returns true if contentType is an HTML media type.
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// injectBaseTag returns the HTML document content with <base href="baseURL">
+// inserted at the beginning of its head. The tag goes after the <html> tag
+// if there is no <head> tag and after the doctype, leading comments and
+// whitespace if there is neither, so the document stays in standards mode.
+// Content with a base tag having href is returned unchanged.
+func injectBaseTag(content []byte, baseURL string) []byte {
+	insertAt := 0
+	offset := 0
+	leading := true
+	z := xhtml.NewTokenizer(bytes.NewReader(content))
+scan:
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			break
+		}
+		offset += len(z.Raw())
+		if leading {
+			switch {
+			case tt == xhtml.DoctypeToken, tt == xhtml.CommentToken,
+				tt == xhtml.TextToken && len(bytes.TrimSpace(z.Raw())) == 0:
+				insertAt = offset
+			default:
+				leading = false
+			}
+		}
+		if tt != xhtml.StartTagToken && tt != xhtml.SelfClosingTagToken && tt != xhtml.EndTagToken {
+			continue
+		}
+		name, hasAttr := z.TagName()
+		switch a := atom.Lookup(name); {
+		case tt == xhtml.EndTagToken && a == atom.Head, a == atom.Body:
+			break scan
+		case a == atom.Html && tt == xhtml.StartTagToken:
+			insertAt = offset
+		case a == atom.Head && tt == xhtml.StartTagToken:
+			insertAt = offset
+		case a == atom.Base && hasAttr:
+			for {
+				key, _, more := z.TagAttr()
+				if string(key) == "href" {
+					return content
+				}
+				if !more {
+					break
+				}
+			}
+		}
+	}
+	tag := `<base href="` + html.EscapeString(baseURL) + `">`
+	injected := make([]byte, 0, len(content)+len(tag))
+	injected = append(injected, content[:insertAt]...)
+	injected = append(injected, tag...)
+	return append(injected, content[insertAt:]...)
+}
@@ -0,0 +1,72 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInjectBaseTag(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{`<html><head lang="en"><title>t</title></head><body></body></html>`,
+			`<html><head lang="en"><base href="http://example.com/a?b=1&amp;c=2"><title>t</title></head><body></body></html>`},
+		{`<!DOCTYPE html><html><body><a href="x">x</a></body></html>`,
+			`<!DOCTYPE html><html><base href="http://example.com/a?b=1&amp;c=2"><body><a href="x">x</a></body></html>`},
+		{`<p>fragment</p>`,
+			`<base href="http://example.com/a?b=1&amp;c=2"><p>fragment</p>`},
+		//the doctype stays first
+		{"<!DOCTYPE html>\n<!-- generated -->\n<title>t</title><p>text</p>",
+			"<!DOCTYPE html>\n<!-- generated -->\n<base href=\"http://example.com/a?b=1&amp;c=2\"><title>t</title><p>text</p>"},
+		//an existing base tag is respected
+		{`<html><head><BASE HREF="/root/"></head></html>`,
+			`<html><head><BASE HREF="/root/"></head></html>`},
+		//base tags with target only don't count
+		{`<head><base target="_blank"></head>`,
+			`<head><base href="http://example.com/a?b=1&amp;c=2"><base target="_blank"></head>`},
+		//base tags out of the head don't count either
+		{`<head></head><body><base href="/x/"></body>`,
+			`<head><base href="http://example.com/a?b=1&amp;c=2"></head><body><base href="/x/"></body>`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, string(injectBaseTag([]byte(tt.content), "http://example.com/a?b=1&c=2")), tt.content)
+	}
+}
+
+func TestBaseFetcher_InjectBaseTag(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new/page", http.StatusFound)
+	})
+	mux.HandleFunc("/new/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head></head><body><a href="next">next</a></body></html>`))
+	})
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(`<head></head>`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.InjectBaseTag = true
+	content, err := fetcher.Fetch(Request{URL: ts.URL + "/old"})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, `<html><head><base href="`+ts.URL+`/new/page"></head><body><a href="next">next</a></body></html>`, string(data))
+
+	//other content is returned as is
+	content, err = fetcher.Fetch(Request{URL: ts.URL + "/text"})
+	assert.NoError(t, err)
+	data, err = ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, `<head></head>`, string(data))
+}
@@ -0,0 +1,135 @@
+package fetch
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+
+	"golang.org/x/net/http2"
+)
+
+// proxyKey is the request context key of the proxy set by Request.ProxyURL.
+type proxyKey struct{}
+
+// requestProxy returns the proxy of the request req belongs to, nil if it
+// is sent with the proxy of BaseFetcher.
+func requestProxy(req *http.Request) *url.URL {
+	proxy, _ := req.Context().Value(proxyKey{}).(*url.URL)
+	return proxy
+}
+
+// proxyTransports are copies of the transport of BaseFetcher sending
+// requests through other proxies, keyed by proxy URL. The zero value is
+// ready to use.
+type proxyTransports struct {
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+}
+
+// get returns the copy of base sending requests through proxy, it is made on first use.
+func (p *proxyTransports) get(base *http.Transport, proxy *url.URL) (*http.Transport, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := proxy.String()
+	if t, ok := p.transports[key]; ok {
+		return t, nil
+	}
+	t := copyTransport(base)
+	t.Proxy = http.ProxyURL(proxy)
+	if err := http2.ConfigureTransport(t); err != nil {
+		return nil, err
+	}
+	if p.transports == nil {
+		p.transports = make(map[string]*http.Transport)
+	}
+	p.transports[key] = t
+	return t, nil
+}
+
+// copyTransport returns a transport configured like t without its
+// connections. TLSNextProto is left out as a copied HTTP/2 upgrade would
+// pool connections with t.
+func copyTransport(t *http.Transport) *http.Transport {
+	var tlsConfig *tls.Config
+	if t.TLSClientConfig != nil {
+		tlsConfig = t.TLSClientConfig.Clone()
+	}
+	return &http.Transport{
+		Proxy:                  t.Proxy,
+		DialContext:            t.DialContext,
+		Dial:                   t.Dial,
+		DialTLS:                t.DialTLS,
+		TLSClientConfig:        tlsConfig,
+		TLSHandshakeTimeout:    t.TLSHandshakeTimeout,
+		DisableKeepAlives:      t.DisableKeepAlives,
+		DisableCompression:     t.DisableCompression,
+		MaxIdleConns:           t.MaxIdleConns,
+		MaxIdleConnsPerHost:    t.MaxIdleConnsPerHost,
+		IdleConnTimeout:        t.IdleConnTimeout,
+		ResponseHeaderTimeout:  t.ResponseHeaderTimeout,
+		ExpectContinueTimeout:  t.ExpectContinueTimeout,
+		ProxyConnectHeader:     t.ProxyConnectHeader,
+		MaxResponseHeaderBytes: t.MaxResponseHeaderBytes,
+	}
+}
+
+// closeIdleConnections closes the idle connections of all the transports.
+func (p *proxyTransports) closeIdleConnections() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, t := range p.transports {
+		t.CloseIdleConnections()
+	}
+}
+
+// baseTransport is the innermost transport of BaseFetcher. It sends
+// requests with the transport of their proxy and keeps track of the
+// requests using connections so connections expiring after MaxConnLifetime
+// are closed once the requests are done.
+type baseTransport struct {
+	bf *BaseFetcher
+}
+
+func (t baseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.bf.transport
+	if proxy := requestProxy(req); proxy != nil {
+		var err error
+		if next, err = t.bf.proxyTransports.get(t.bf.transport, proxy); err != nil {
+			return nil, err
+		}
+	}
+	if t.bf.MaxConnLifetime <= 0 {
+		return next.RoundTrip(req)
+	}
+	var conn *lifetimeConn
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			//the transport may try more than one connection
+			if conn != nil {
+				conn.release()
+			}
+			conn, _ = t.bf.lifetimeConns.lookup(info.Conn)
+			if conn != nil {
+				conn.acquire()
+			}
+		},
+	}
+	resp, err := next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if conn == nil {
+		return resp, err
+	}
+	if err != nil {
+		conn.release()
+		return resp, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: conn.release}
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of the transports.
+func (t baseTransport) CloseIdleConnections() {
+	t.bf.transport.CloseIdleConnections()
+	t.bf.proxyTransports.closeIdleConnections()
+}
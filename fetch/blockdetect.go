@@ -0,0 +1,82 @@
+package fetch
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// DefaultBlockPatterns match widespread block pages served with success
+// status: anti-bot challenges of Cloudflare and DataDome, captcha pages,
+// "access denied" pages and soft 404 pages.
+var DefaultBlockPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)<title>\s*(just a moment|attention required)`),
+	//challenge interstitials, not the challenge-platform script Cloudflare
+	//injects into ordinary pages
+	regexp.MustCompile(`(?i)cf-browser-verification|window\._cf_chl_opt|id="challenge-form"`),
+	regexp.MustCompile(`(?i)captcha-delivery\.com`),
+	regexp.MustCompile(`(?i)<title>[^<]*(captcha|are you a robot|robot check)`),
+	regexp.MustCompile(`(?i)<title>[^<]*(access denied|403 forbidden)`),
+	regexp.MustCompile(`(?i)<title>[^<]*(404 not found|page not found)`),
+}
+
+// defaultBlockMaxBytes is the number of bytes of the body BlockDetector inspects by default.
+const defaultBlockMaxBytes = 64 << 10
+
+// BlockDetector recognizes block pages masquerading as successful
+// responses by matching the beginning of the body against Patterns.
+type BlockDetector struct {
+	Patterns []*regexp.Regexp
+	// MaxBytes is the number of bytes of the body inspected. It defaults to 64 KB.
+	MaxBytes int
+}
+
+// NewBlockDetector returns BlockDetector matching DefaultBlockPatterns and
+// the regular expressions patterns.
+func NewBlockDetector(patterns ...string) (*BlockDetector, error) {
+	d := &BlockDetector{Patterns: append([]*regexp.Regexp{}, DefaultBlockPatterns...)}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		d.Patterns = append(d.Patterns, re)
+	}
+	return d, nil
+}
+
+// Match returns the first pattern matching body or an empty string if
+// body doesn't look like a block page.
+func (d *BlockDetector) Match(body []byte) string {
+	for _, re := range d.Patterns {
+		if re.Match(body) {
+			return re.String()
+		}
+	}
+	return ""
+}
+
+// check returns errs.Blocked if the body of resp is a block page. The
+// inspected beginning of the body stays available to read.
+func (d *BlockDetector) check(resp *http.Response) error {
+	max := d.MaxBytes
+	if max <= 0 {
+		max = defaultBlockMaxBytes
+	}
+	head := make([]byte, max)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		resp.Body.Close()
+		return err
+	}
+	head = head[:n]
+	if pattern := d.Match(head); pattern != "" {
+		resp.Body.Close()
+		return &errs.Blocked{URL: resp.Request.URL.String(), Pattern: pattern}
+	}
+	resp.Body = readCloser{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	return nil
+}
@@ -0,0 +1,67 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockDetector_Match(t *testing.T) {
+	d, err := NewBlockDetector(`(?i)you have been banned`)
+	assert.NoError(t, err)
+	blocked := []string{
+		`<html><head><title>Just a moment...</title></head><body><div id="cf-browser-verification"></div></body></html>`,
+		`<html><head><title>Attention Required! | Cloudflare</title></head></html>`,
+		`<html><body><script>window._cf_chl_opt={cvId: '3',cType: 'managed'};</script></body></html>`,
+		`<html><body><form id="challenge-form" action="/?__cf_chl_f_tk=abc" method="POST"></form></body></html>`,
+		`<html><script src="https://ct.captcha-delivery.com/c.js"></script></html>`,
+		`<html><head><title>Robot Check</title></head></html>`,
+		`<HTML><HEAD><TITLE>Access Denied</TITLE></HEAD><BODY>You don't have permission to access this server.</BODY></HTML>`,
+		`<html><head><title>Page Not Found - Shop</title></head></html>`,
+		`<html><body><h1>You have been banned</h1></body></html>`,
+	}
+	for _, body := range blocked {
+		assert.NotEmpty(t, d.Match([]byte(body)), body)
+	}
+	assert.Empty(t, d.Match([]byte(`<html><head><title>Products</title></head><body>Access denied pages are rare here.</body></html>`)))
+	//Cloudflare JavaScript detections are injected into ordinary pages
+	assert.Empty(t, d.Match([]byte(`<html><head><title>Products</title></head><body><h1>Products</h1>`+
+		`<script>(function(){var a=document.createElement('script');a.src='/cdn-cgi/challenge-platform/scripts/jsd/main.js';`+
+		`window.__CF$cv$params={r:'8c1f',t:'MTcy'};document.getElementsByTagName('head')[0].appendChild(a);})();</script></body></html>`)))
+
+	_, err = NewBlockDetector(`(`)
+	assert.Error(t, err)
+}
+
+func TestBaseFetcher_BlockDetector(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/blocked" {
+			w.Write([]byte(`<html><head><title>Just a moment...</title></head></html>`))
+			return
+		}
+		w.Write([]byte(`<html><head><title>Products</title></head><body>` + strings.Repeat("a", 100) + `</body></html>`))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.BlockDetector, err = NewBlockDetector()
+	assert.NoError(t, err)
+	fetcher.BlockDetector.MaxBytes = 32
+
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/blocked"})
+	assert.IsType(t, &errs.Blocked{}, err)
+
+	//the inspected part of the body is returned too
+	content, err := fetcher.Fetch(Request{URL: ts.URL})
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(content)
+		content.Close()
+		assert.Len(t, body, 162)
+	}
+}
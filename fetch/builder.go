@@ -0,0 +1,87 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RequestBuilder builds Request step by step, e.g.
+//
+//	r, err := NewRequest("http://example.com/login").
+//		Form(url.Values{"user": {"name"}}).
+//		Header("Referer", "http://example.com/").
+//		Chrome().
+//		Build()
+type RequestBuilder struct {
+	r Request
+}
+
+// NewRequest starts building a GET request of rawurl for BaseFetcher.
+func NewRequest(rawurl string) *RequestBuilder {
+	return &RequestBuilder{r: Request{Type: string(Base), URL: rawurl}}
+}
+
+// Method sets the HTTP method.
+func (b *RequestBuilder) Method(method string) *RequestBuilder {
+	b.r.Method = strings.ToUpper(method)
+	return b
+}
+
+// Form sets the form data sent with a POST request.
+func (b *RequestBuilder) Form(values url.Values) *RequestBuilder {
+	b.r.FormData = values.Encode()
+	return b
+}
+
+// Header adds the header name: value.
+func (b *RequestBuilder) Header(name, value string) *RequestBuilder {
+	if b.r.Headers == nil {
+		b.r.Headers = http.Header{}
+	}
+	b.r.Headers.Add(name, value)
+	return b
+}
+
+// UserToken sets the token identifying the cookies of a user.
+func (b *RequestBuilder) UserToken(token string) *RequestBuilder {
+	b.r.UserToken = token
+	return b
+}
+
+// Context sets the context carrying the deadline and cancelation of the request.
+func (b *RequestBuilder) Context(ctx context.Context) *RequestBuilder {
+	b.r.Context = ctx
+	return b
+}
+
+// Chrome makes the request to be rendered by ChromeFetcher.
+func (b *RequestBuilder) Chrome() *RequestBuilder {
+	b.r.Type = string(Chrome)
+	return b
+}
+
+// InfiniteScroll makes ChromeFetcher scroll pages with continuous scrolling
+// to the bottom. It implies Chrome.
+func (b *RequestBuilder) InfiniteScroll() *RequestBuilder {
+	b.r.InfiniteScroll = true
+	return b.Chrome()
+}
+
+// Build returns the built Request. It returns an error if the URL is
+// invalid or if form data is set together with a method other than POST,
+// as form data is always sent with POST.
+func (b *RequestBuilder) Build() (Request, error) {
+	if _, err := url.ParseRequestURI(b.r.URL); err != nil {
+		return Request{}, err
+	}
+	if b.r.FormData != "" {
+		if b.r.Method != "" && b.r.Method != "POST" {
+			return Request{}, fmt.Errorf("form data can't be sent with %s method", b.r.Method)
+		}
+		b.r.Method = "POST"
+	}
+	return b.r, nil
+}
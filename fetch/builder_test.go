@@ -0,0 +1,41 @@
+package fetch
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestBuilder(t *testing.T) {
+	r, err := NewRequest("http://example.com/login").
+		Method("post").
+		Form(url.Values{"user": {"name"}, "password": {"p&ss"}}).
+		Header("Referer", "http://example.com/").
+		UserToken("token").
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, Request{
+		Type:      "Base",
+		URL:       "http://example.com/login",
+		Method:    "POST",
+		FormData:  "password=p%26ss&user=name",
+		Headers:   http.Header{"Referer": {"http://example.com/"}},
+		UserToken: "token",
+	}, r)
+
+	//form data implies POST
+	r, err = NewRequest("http://example.com/login").Form(url.Values{"user": {"name"}}).Chrome().Build()
+	assert.NoError(t, err)
+	assert.Equal(t, Request{Type: "Chrome", URL: "http://example.com/login", Method: "POST", FormData: "user=name"}, r)
+
+	r, err = NewRequest("http://example.com/feed").InfiniteScroll().Build()
+	assert.NoError(t, err)
+	assert.Equal(t, Request{Type: "Chrome", URL: "http://example.com/feed", InfiniteScroll: true}, r)
+
+	_, err = NewRequest("http://example.com/login").Method("GET").Form(url.Values{"user": {"name"}}).Build()
+	assert.Error(t, err)
+	_, err = NewRequest("example.com").Build()
+	assert.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package fetch
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/network"
+)
+
+// saveBrowserCookies stores the cookies the browser holds for the rendered
+// page and its frames in the cookie jar. Unlike Set-Cookie headers they
+// include cookies set by JavaScript via document.cookie. rawurl is the URL
+// cookies without Domain are stored for.
+func (f *ChromeFetcher) saveBrowserCookies(ctx context.Context, rawurl string) error {
+	jar := f.getCookieJar()
+	if jar == nil {
+		return nil
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+	//without URLs the cookies of the page and all of its subframes are returned
+	reply, err := f.cdpClient.Network.GetCookies(ctx, network.NewGetCookiesArgs())
+	if err != nil {
+		return err
+	}
+	setCookiesByDomain(jar, browserCookies(reply.Cookies), u)
+	return nil
+}
+
+// browserCookies converts cookies reported by the browser to http.Cookie.
+func browserCookies(cookies []network.Cookie) []*http.Cookie {
+	httpCookies := make([]*http.Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		cookie := &http.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HTTPOnly,
+		}
+		if !c.Session && c.Expires > 0 {
+			sec, frac := math.Modf(c.Expires)
+			cookie.Expires = time.Unix(int64(sec), int64(frac*1e9))
+		}
+		httpCookies = append(httpCookies, cookie)
+	}
+	return httpCookies
+}
@@ -0,0 +1,62 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/runtime"
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// defaultCloudflareMaxWait is how long ChromeFetcher waits for a Cloudflare
+// challenge to resolve by default. Challenges usually take about 5 seconds.
+const defaultCloudflareMaxWait = 30 * time.Second
+
+// cloudflarePoll is the interval the page is checked for a challenge at.
+const cloudflarePoll = 500 * time.Millisecond
+
+// cloudflareScript evaluates to true while the page is a Cloudflare
+// "Please wait" interstitial, i.e. until the challenge is solved and the
+// browser navigates to the real content. Only the markup and titles of the
+// interstitial count, Cloudflare injects challenge-platform scripts into
+// ordinary pages too.
+const cloudflareScript = `(() => {
+  if (/^(just a moment\.\.\.|attention required! \| cloudflare|please wait\.\.\. \| cloudflare)$/i.test(document.title.trim())) {
+    return true;
+  }
+  return !!document.querySelector(
+    "#challenge-form, #challenge-running, #cf-challenge-running, .cf-browser-verification");
+})()`
+
+// solveCloudflare waits for the Cloudflare JavaScript challenge of the
+// loaded page to be solved by the browser. The page is polled rather than
+// waited for in a single script as solving the challenge navigates away,
+// destroying the script context. errs.Blocked is returned if the challenge
+// is still there after CloudflareMaxWait.
+func (f *ChromeFetcher) solveCloudflare(ctx context.Context, url string) error {
+	maxWait := f.CloudflareMaxWait
+	if maxWait <= 0 {
+		maxWait = defaultCloudflareMaxWait
+	}
+	deadline := time.Now().Add(maxWait)
+	args := runtime.NewEvaluateArgs(cloudflareScript).SetReturnByValue(true)
+	for {
+		//evaluation fails while the page navigates, it is retried then
+		reply, err := f.cdpClient.Runtime.Evaluate(ctx, args)
+		if err == nil && reply.ExceptionDetails == nil {
+			var challenged bool
+			if err := json.Unmarshal(reply.Result.Value, &challenged); err == nil && !challenged {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return &errs.Blocked{URL: url, Pattern: "Cloudflare challenge"}
+		}
+		select {
+		case <-time.After(cloudflarePoll):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mafredri/cdp"
+	"github.com/mafredri/cdp/rpcc"
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+//mockChrome serves the Chrome Debugging Protocol answering Runtime.evaluate
+//calls with the values of evaluate. Evaluated expressions are sent to scripts.
+func mockChrome(t *testing.T, evaluate func(expression string) interface{}) (*httptest.Server, chan string) {
+	scripts := make(chan string, 100)
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		for {
+			var call struct {
+				ID     uint64 `json:"id"`
+				Method string `json:"method"`
+				Params struct {
+					Expression string `json:"expression"`
+				} `json:"params"`
+			}
+			if err := conn.ReadJSON(&call); err != nil {
+				return
+			}
+			scripts <- call.Params.Expression
+			result := map[string]interface{}{
+				"result": map[string]interface{}{"type": "boolean", "value": evaluate(call.Params.Expression)},
+			}
+			if err := conn.WriteJSON(map[string]interface{}{"id": call.ID, "result": result}); err != nil {
+				return
+			}
+		}
+	}))
+	return ts, scripts
+}
+
+func TestChromeFetcher_SolveCloudflare(t *testing.T) {
+	//the challenge is solved on the third check
+	var checks int32
+	ts, scripts := mockChrome(t, func(string) interface{} {
+		return atomic.AddInt32(&checks, 1) < 3
+	})
+	defer ts.Close()
+	conn, err := rpcc.Dial("ws" + strings.TrimPrefix(ts.URL, "http"))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	f := &ChromeFetcher{cdpClient: cdp.NewClient(conn), CloudflareMaxWait: 5 * time.Second}
+	assert.NoError(t, f.solveCloudflare(context.Background(), "http://example.com"))
+	assert.Equal(t, cloudflareScript, <-scripts)
+	//ordinary pages get the challenge-platform script injected as well
+	assert.NotContains(t, cloudflareScript, "challenge-platform")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&checks))
+
+	//a challenge never solved blocks the fetch
+	atomic.StoreInt32(&checks, -100)
+	f.CloudflareMaxWait = 100 * time.Millisecond
+	err = f.solveCloudflare(context.Background(), "http://example.com")
+	assert.IsType(t, &errs.Blocked{}, err)
+}
@@ -0,0 +1,128 @@
+package fetch
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// lifetimeConn is a connection closed once it is older than its lifetime
+// and no request is using it.
+type lifetimeConn struct {
+	net.Conn
+	timer   *time.Timer
+	mu      sync.Mutex
+	inUse   int
+	expired bool
+	// conns is the registry the connection is removed from once closed.
+	conns *lifetimeConns
+}
+
+func newLifetimeConn(conn net.Conn, lifetime time.Duration, conns *lifetimeConns) *lifetimeConn {
+	c := &lifetimeConn{Conn: conn, conns: conns}
+	conns.add(c)
+	c.timer = time.AfterFunc(lifetime, c.expire)
+	return c
+}
+
+// expire closes the connection if it is idle or marks it to be closed by
+// the last request using it. The pool of the transport drops closed idle
+// connections.
+func (c *lifetimeConn) expire() {
+	c.mu.Lock()
+	c.expired = true
+	idle := c.inUse == 0
+	c.mu.Unlock()
+	if idle {
+		c.Conn.Close()
+	}
+}
+
+// acquire marks the connection as being used by a request.
+func (c *lifetimeConn) acquire() {
+	c.mu.Lock()
+	c.inUse++
+	c.mu.Unlock()
+}
+
+// release marks a request using the connection as done.
+func (c *lifetimeConn) release() {
+	c.mu.Lock()
+	c.inUse--
+	closing := c.expired && c.inUse == 0
+	c.mu.Unlock()
+	if closing {
+		c.Conn.Close()
+	}
+}
+
+func (c *lifetimeConn) Close() error {
+	c.timer.Stop()
+	c.conns.remove(c)
+	return c.Conn.Close()
+}
+
+// lifetimeConns are the lifetimeConns dialed by BaseFetcher, registered by
+// local address to be found once they are wrapped by the transport, e.g. in
+// TLS. Addresses of connections made by net.Dialer are pointers unique to
+// their connection, which are passed through by the wrappers. The zero value
+// is ready to use.
+type lifetimeConns struct {
+	mu    sync.Mutex
+	conns map[net.Addr]*lifetimeConn
+}
+
+// key returns the registry key of conn or nil if its address can't serve as one.
+func (l *lifetimeConns) key(conn net.Conn) net.Addr {
+	switch addr := conn.LocalAddr().(type) {
+	case *net.TCPAddr:
+		if addr != nil {
+			return addr
+		}
+	case *net.UnixAddr:
+		if addr != nil {
+			return addr
+		}
+	}
+	return nil
+}
+
+func (l *lifetimeConns) add(c *lifetimeConn) {
+	key := l.key(c.Conn)
+	if key == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns == nil {
+		l.conns = make(map[net.Addr]*lifetimeConn)
+	}
+	l.conns[key] = c
+}
+
+func (l *lifetimeConns) remove(c *lifetimeConn) {
+	key := l.key(c.Conn)
+	if key == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns[key] == c {
+		delete(l.conns, key)
+	}
+}
+
+// lookup returns the lifetimeConn conn is or wraps.
+func (l *lifetimeConns) lookup(conn net.Conn) (*lifetimeConn, bool) {
+	if c, ok := conn.(*lifetimeConn); ok {
+		return c, true
+	}
+	key := l.key(conn)
+	if key == nil {
+		return nil, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, ok := l.conns[key]
+	return c, ok
+}
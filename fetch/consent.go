@@ -0,0 +1,96 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/runtime"
+)
+
+// consentSelectors match "accept cookies" buttons of widespread consent
+// management platforms and cookie banners.
+var consentSelectors = []string{
+	"#onetrust-accept-btn-handler",
+	"#accept-recommended-btn-handler",
+	"#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll",
+	"#CybotCookiebotDialogBodyButtonAccept",
+	".qc-cmp2-summary-buttons button[mode=primary]",
+	"#didomi-notice-agree-button",
+	".fc-cta-consent",
+	"button.sp_choice_type_11",
+	".cc-allow",
+	".cc-dismiss",
+	"#cookie-accept",
+	"#accept-cookies",
+	"#acceptCookies",
+	"button[data-testid=cookie-policy-banner-accept]",
+	"button[aria-label*=accept i]",
+	"[id*=cookie] button[id*=accept]",
+	"[class*=cookie] button[class*=accept]",
+}
+
+// consentWait is how long the consent script keeps looking for a banner
+// which is rendered after the page is loaded.
+const consentWait = 3 * time.Second
+
+// consentScript returns JavaScript clicking the first visible element
+// matching one of selectors, polling for consentWait. Banners in same-origin
+// iframes are searched too. The script resolves to the matched selector or
+// an empty string.
+func consentScript(selectors []string) string {
+	list, _ := json.Marshal(selectors)
+	return fmt.Sprintf(`new Promise(resolve => {
+  const selectors = %s;
+  const docs = () => [document].concat(Array.from(document.querySelectorAll("iframe"))
+    .map(f => { try { return f.contentDocument; } catch (e) { return null; } })
+    .filter(d => d));
+  const click = () => {
+    for (const doc of docs()) {
+      for (const sel of selectors) {
+        let el;
+        try { el = doc.querySelector(sel); } catch (e) { continue; }
+        if (el && el.offsetParent !== null) {
+          el.click();
+          return sel;
+        }
+      }
+    }
+    return "";
+  };
+  const deadline = Date.now() + %d;
+  const poll = () => {
+    const sel = click();
+    if (sel || Date.now() > deadline) {
+      resolve(sel);
+      return;
+    }
+    setTimeout(poll, 250);
+  };
+  poll();
+})`, list, consentWait/time.Millisecond)
+}
+
+// acceptCookies clicks the cookie consent button of the loaded page.
+// Custom selectors are tried before the default ones.
+func (f *ChromeFetcher) acceptCookies(ctx context.Context, custom []string) error {
+	selectors := append(append([]string{}, custom...), consentSelectors...)
+	args := runtime.NewEvaluateArgs(consentScript(selectors)).
+		SetAwaitPromise(true).
+		SetReturnByValue(true).
+		SetUserGesture(true)
+	reply, err := f.cdpClient.Runtime.Evaluate(ctx, args)
+	if err != nil {
+		return err
+	}
+	if reply.ExceptionDetails != nil {
+		return fmt.Errorf("consent script failed: %s", reply.ExceptionDetails.Text)
+	}
+	var clicked string
+	if err := json.Unmarshal(reply.Result.Value, &clicked); err == nil && clicked != "" {
+		// let the page remove the banner and load the consented content
+		time.Sleep(500 * time.Millisecond)
+	}
+	return nil
+}
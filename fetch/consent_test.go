@@ -0,0 +1,15 @@
+package fetch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsentScript(t *testing.T) {
+	script := consentScript(append([]string{`button[title="OK"]`}, consentSelectors...))
+	assert.Contains(t, script, `["button[title=\"OK\"]","#onetrust-accept-btn-handler"`)
+	assert.True(t, strings.HasPrefix(script, "new Promise("))
+	assert.Contains(t, script, "const deadline = Date.now() + 3000;")
+}
@@ -0,0 +1,44 @@
+package fetch
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+// checkContentType returns errs.UnsupportedContentType if the media type of
+// resp is one of ErrorContentTypes. Without Content-Type header the type is
+// detected from the beginning of the body, which stays available to read.
+func (bf *BaseFetcher) checkContentType(resp *http.Response) error {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		head := make([]byte, sniffLen)
+		n, err := io.ReadFull(resp.Body, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			resp.Body.Close()
+			return err
+		}
+		head = head[:n]
+		contentType = http.DetectContentType(head)
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	for _, t := range bf.ErrorContentTypes {
+		t = strings.ToLower(t)
+		if t == mediaType || strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1]) {
+			resp.Body.Close()
+			return &errs.UnsupportedContentType{URL: resp.Request.URL.String(), ContentType: mediaType}
+		}
+	}
+	return nil
+}
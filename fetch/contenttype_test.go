@@ -0,0 +1,58 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_ErrorContentTypes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "Application/JSON; charset=utf-8")
+			w.Write([]byte(`{"error":"not found"}`))
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("error"))
+		case "/sniffed":
+			//no Content-Type is sent for an empty type
+			w.Header()["Content-Type"] = nil
+			w.Write([]byte("%PDF-1.4 document"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>article</body></html>"))
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.ErrorContentTypes = []string{"application/json", "text/plain", "application/*"}
+	for _, path := range []string{"/json", "/text", "/sniffed"} {
+		_, err = fetcher.Fetch(Request{URL: ts.URL + path})
+		assert.IsType(t, &errs.UnsupportedContentType{}, err, path)
+	}
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/json"})
+	if assert.IsType(t, &errs.UnsupportedContentType{}, err) {
+		assert.Equal(t, "application/json", err.(*errs.UnsupportedContentType).ContentType)
+	}
+
+	content, err := fetcher.Fetch(Request{URL: ts.URL + "/article"})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><body>article</body></html>", string(data))
+
+	//sniffed bytes are put back
+	fetcher.ErrorContentTypes = []string{"image/*"}
+	content, err = fetcher.Fetch(Request{URL: ts.URL + "/sniffed"})
+	assert.NoError(t, err)
+	data, err = ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4 document", string(data))
+}
@@ -0,0 +1,46 @@
+package fetch
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// WithEncryptedCookieStore encrypts the cookies of users persisted to
+// storage with AES-GCM using key, which is 16, 24 or 32 bytes long to
+// select AES-128, AES-192 or AES-256. Cookies are decrypted transparently
+// on load, cookies which can't be decrypted with key are handled according
+// to CookieErrors.
+func WithEncryptedCookieStore(key []byte) ServiceOption {
+	return func(fs *FetchService) error {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return err
+		}
+		fs.cookieCipher, err = cipher.NewGCM(block)
+		return err
+	}
+}
+
+// encryptCookies returns the cookies serialized in data of the user
+// identified by token encrypted with aead, prefixed by the random nonce
+// used. The token is authenticated along, so the cookies of one user can't
+// be decrypted as the cookies of another one.
+func encryptCookies(aead cipher.AEAD, data []byte, token string) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, []byte(token)), nil
+}
+
+// decryptCookies returns the cookies of token encrypted by encryptCookies.
+func decryptCookies(aead cipher.AEAD, data []byte, token string) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("encrypted cookies too short")
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, []byte(token))
+}
@@ -0,0 +1,153 @@
+package fetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// jsonCookie is the portable JSON representation of a cookie used by
+// ExportCookiesJSON, independent of the field names of http.Cookie.
+type jsonCookie struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Domain string `json:"domain"`
+	// HostOnly cookies are sent to Domain only, not to its subdomains.
+	HostOnly bool   `json:"hostOnly,omitempty"`
+	Path     string `json:"path,omitempty"`
+	// Expires is in RFC 3339 format. Session cookies have none.
+	Expires  string `json:"expires,omitempty"`
+	MaxAge   int    `json:"maxAge,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	// SameSite is "Lax", "Strict" or "None".
+	SameSite string `json:"sameSite,omitempty"`
+}
+
+var sameSiteModes = []string{"Lax", "Strict", "None"}
+
+// sameSite returns the SameSite attribute of c. net/http doesn't parse it so
+// it is kept in the unparsed attributes of the cookie.
+func sameSite(c *http.Cookie) string {
+	for _, attr := range c.Unparsed {
+		if kv := strings.SplitN(attr, "=", 2); len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), "samesite") {
+			for _, mode := range sameSiteModes {
+				if strings.EqualFold(strings.TrimSpace(kv[1]), mode) {
+					return mode
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// ExportCookiesJSON returns all the cookies in the cookie jar of f as a JSON
+// array of objects with name, value, domain, hostOnly, path, expires, maxAge,
+// secure, httpOnly and sameSite attributes, e.g. to inspect them or to hand the
+// session over to another service. Cookies are sorted by domain, path and
+// name. A fetcher without cookie jar exports an empty array.
+func ExportCookiesJSON(f Fetcher) ([]byte, error) {
+	cookies := []jsonCookie{}
+	if jar := f.getCookieJar(); jar != nil {
+		for _, c := range jar.AllCookies() {
+			jc := jsonCookie{
+				Name:     c.Name,
+				Value:    c.Value,
+				Domain:   c.Domain,
+				Path:     c.Path,
+				MaxAge:   c.MaxAge,
+				Secure:   c.Secure,
+				HTTPOnly: c.HttpOnly,
+				SameSite: sameSite(&c.Cookie),
+			}
+			if jc.Domain == "" {
+				u, err := url.Parse(c.URL)
+				if err != nil {
+					return nil, err
+				}
+				jc.Domain = u.Hostname()
+				jc.HostOnly = true
+			}
+			if !c.Expires.IsZero() {
+				jc.Expires = c.Expires.UTC().Format(time.RFC3339)
+			}
+			cookies = append(cookies, jc)
+		}
+	}
+	sort.Slice(cookies, func(i, j int) bool {
+		a, b := cookies[i], cookies[j]
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Name < b.Name
+	})
+	return json.MarshalIndent(cookies, "", "  ")
+}
+
+// ImportCookiesJSON stores the cookies exported by ExportCookiesJSON in the
+// cookie jar of f, creating one if f has none. Every cookie has to have a
+// domain.
+func ImportCookiesJSON(f Fetcher, data []byte) error {
+	var imported []jsonCookie
+	if err := json.Unmarshal(data, &imported); err != nil {
+		return err
+	}
+	cookies := make([]StoredCookie, len(imported))
+	for i, jc := range imported {
+		if jc.Domain == "" {
+			return fmt.Errorf("cookie %s has no domain", jc.Name)
+		}
+		scheme := "http"
+		if jc.Secure {
+			scheme = "https"
+		}
+		u := &url.URL{Scheme: scheme, Host: strings.TrimPrefix(jc.Domain, "."), Path: "/"}
+		c := http.Cookie{
+			Name:     jc.Name,
+			Value:    jc.Value,
+			Domain:   jc.Domain,
+			Path:     jc.Path,
+			MaxAge:   jc.MaxAge,
+			Secure:   jc.Secure,
+			HttpOnly: jc.HTTPOnly,
+		}
+		if jc.Expires != "" {
+			expires, err := time.Parse(time.RFC3339, jc.Expires)
+			if err != nil {
+				return fmt.Errorf("cookie %s: %s", jc.Name, err)
+			}
+			c.Expires = expires
+		}
+		if jc.HostOnly {
+			c.Domain = ""
+		}
+		if jc.SameSite != "" {
+			c.Unparsed = []string{"SameSite=" + jc.SameSite}
+			if sameSite(&c) == "" {
+				return fmt.Errorf("cookie %s: unknown SameSite %q", jc.Name, jc.SameSite)
+			}
+		}
+		cookies[i] = StoredCookie{Cookie: c, URL: u.String()}
+	}
+	jar := f.getCookieJar()
+	if jar == nil {
+		cJar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		if err != nil {
+			return err
+		}
+		jar = NewCookieJar(cJar)
+		f.setCookieJar(jar)
+	}
+	setStoredCookies(jar, cookies, nil)
+	return nil
+}
@@ -0,0 +1,328 @@
+package fetch
+
+import (
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"golang.org/x/net/publicsuffix"
+)
+
+// CookieJar stores cookies of fetched pages. Besides the methods of
+// http.CookieJar it is able to list all the cookies it holds, which is needed
+// to persist or share them e.g. between distributed workers.
+type CookieJar interface {
+	http.CookieJar
+	// AllCookies returns all unexpired cookies stored in the jar.
+	AllCookies() []StoredCookie
+}
+
+// StoredCookie is a cookie held by CookieJar together with the URL it was
+// set for. Host-only cookies have no Domain. Setting the cookie for URL
+// again restores it, see setStoredCookies.
+type StoredCookie struct {
+	http.Cookie
+	URL string `json:"URL,omitempty"`
+}
+
+// NewCookieJar adapts jar to CookieJar. Cookie matching is left to jar while
+// the adapter keeps a copy of every cookie jar accepts to be able to list
+// them. Expires attributes net/http fails to parse, which would turn cookies
+// into session cookies, are parsed leniently, see parseLenientExpires.
+func NewCookieJar(jar *cookiejar.Jar) CookieJar {
+	return &jarAdapter{
+		jar:     jar,
+		cookies: make(map[string]*StoredCookie),
+	}
+}
+
+// NewStrictCookieJar is like NewCookieJar but rejects cookies with an Expires
+// attribute that is not a valid HTTP date instead of parsing it leniently.
+func NewStrictCookieJar(jar *cookiejar.Jar) CookieJar {
+	return &jarAdapter{
+		jar:          jar,
+		cookies:      make(map[string]*StoredCookie),
+		strictExpiry: true,
+	}
+}
+
+type jarAdapter struct {
+	jar          *cookiejar.Jar
+	strictExpiry bool
+	mu           sync.Mutex
+	cookies      map[string]*StoredCookie
+}
+
+// SetCookies implements http.CookieJar. Cookies are kept under the same
+// domain, path and name as cookiejar.Jar keeps them. Cookies the jar
+// rejects, e.g. for foreign or public suffix domains, are not kept. Max-Age
+// is kept as the Expires time it gives, so the lifetime of a cookie doesn't
+// start over when it is restored.
+func (j *jarAdapter) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	cookies = j.checkExpiry(cookies)
+	j.jar.SetCookies(u, cookies)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return
+	}
+	host := normalizeHost(u.Hostname())
+	origin := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}).String()
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, c := range cookies {
+		domain, hostOnly, ok := cookieDomain(host, c.Domain)
+		if !ok {
+			continue
+		}
+		stored := &StoredCookie{Cookie: *c, URL: origin}
+		stored.Domain = domain
+		if hostOnly {
+			stored.Domain = ""
+		}
+		if stored.Path == "" || stored.Path[0] != '/' {
+			stored.Path = defaultCookiePath(u.Path)
+		}
+		key := domain + ";" + stored.Path + ";" + stored.Name
+		if stored.MaxAge > 0 {
+			stored.Expires = now.Add(time.Duration(stored.MaxAge) * time.Second)
+			stored.MaxAge = 0
+		}
+		if stored.MaxAge < 0 || (!stored.Expires.IsZero() && stored.Expires.Before(now)) {
+			delete(j.cookies, key)
+			continue
+		}
+		j.cookies[key] = stored
+	}
+}
+
+// cookieDomain returns the domain a cookie with Domain attribute domain set
+// by host is kept under and whether it is a host-only cookie, following the
+// rules of cookiejar.Jar with the public suffix list. It reports false if
+// the jar rejects the cookie.
+func cookieDomain(host, domain string) (string, bool, bool) {
+	if domain == "" {
+		return host, true, true
+	}
+	if net.ParseIP(host) != nil {
+		return "", false, false
+	}
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	if domain == "" || domain[0] == '.' || domain[len(domain)-1] == '.' {
+		return "", false, false
+	}
+	if publicsuffix.List.PublicSuffix(domain) == domain {
+		//a public suffix is only accepted as the host itself
+		return host, true, host == domain
+	}
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return "", false, false
+	}
+	return domain, false, true
+}
+
+// defaultCookiePath returns the path of cookies set without Path by a
+// request for path, the directory of path.
+func defaultCookiePath(path string) string {
+	if path == "" || path[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndex(path, "/")
+	if i == 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+// checkExpiry returns cookies with malformed Expires attributes parsed
+// leniently or, in strict mode, left out.
+func (j *jarAdapter) checkExpiry(cookies []*http.Cookie) []*http.Cookie {
+	checked := make([]*http.Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		raw := rawExpires(c)
+		if raw == "" || !c.Expires.IsZero() {
+			checked = append(checked, c)
+			continue
+		}
+		if j.strictExpiry {
+			continue
+		}
+		fixed := *c
+		fixed.Expires, _ = parseLenientExpires(raw)
+		checked = append(checked, &fixed)
+	}
+	return checked
+}
+
+// rawExpires returns the Expires attribute of c as sent by the server.
+// net/http leaves attributes with invalid characters, e.g. quoted dates, unparsed.
+func rawExpires(c *http.Cookie) string {
+	if c.RawExpires != "" {
+		return c.RawExpires
+	}
+	for _, attr := range c.Unparsed {
+		if kv := strings.SplitN(attr, "=", 2); len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), "expires") {
+			return kv[1]
+		}
+	}
+	return ""
+}
+
+// lenientExpiresLayouts are date formats seen in Expires attributes besides
+// the ones accepted by net/http.
+var lenientExpiresLayouts = []string{
+	"Mon, _2-Jan-2006 15:04:05 MST",
+	"Mon, _2-Jan-06 15:04:05 MST",
+	"Monday, _2-Jan-06 15:04:05 MST",
+	"Monday, _2-Jan-2006 15:04:05 MST",
+	"Mon, _2 Jan 2006 15:04:05 -0700",
+	"Mon, _2 Jan 2006 15:04:05 MST",
+	"Mon, _2 Jan 2006 15:04:05",
+	"Mon, _2-Jan-2006 15:04:05",
+	"Mon _2 Jan 2006 15:04:05 MST",
+	"Mon, _2 Jan 06 15:04:05 MST",
+	"_2 Jan 2006 15:04:05 MST",
+	"_2-Jan-2006 15:04:05 MST",
+	time.ANSIC,
+	time.RFC3339,
+}
+
+// parseLenientExpires parses the Expires attribute of a cookie in one of
+// lenientExpiresLayouts. Dates without time zone are taken as GMT.
+func parseLenientExpires(raw string) (time.Time, bool) {
+	raw = strings.TrimSpace(strings.Trim(raw, `"`))
+	for _, layout := range lenientExpiresLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Cookies implements http.CookieJar.
+func (j *jarAdapter) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// AllCookies implements CookieJar.
+func (j *jarAdapter) AllCookies() []StoredCookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	cookies := []StoredCookie{}
+	for key, c := range j.cookies {
+		if !c.Expires.IsZero() && c.Expires.Before(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		cookies = append(cookies, *c)
+	}
+	return cookies
+}
+
+// NewSyncCookieJar wraps jar so that its methods are serialized by a mutex.
+// It makes a CookieJar which is not safe for concurrent use, e.g. a custom
+// one, safe to share between concurrent fetches.
+func NewSyncCookieJar(jar CookieJar) CookieJar {
+	return &syncJar{jar: jar}
+}
+
+type syncJar struct {
+	mu  sync.Mutex
+	jar CookieJar
+}
+
+// SetCookies implements http.CookieJar.
+func (j *syncJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar.SetCookies(u, cookies)
+}
+
+// Cookies implements http.CookieJar.
+func (j *syncJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jar.Cookies(u)
+}
+
+// AllCookies implements CookieJar.
+func (j *syncJar) AllCookies() []StoredCookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jar.AllCookies()
+}
+
+// cookiesForURL returns the cookies from jar to be sent to rawurl.
+func cookiesForURL(jar CookieJar, rawurl string) ([]*http.Cookie, error) {
+	u, err := url.ParseRequestURI(rawurl)
+	if err != nil {
+		return nil, &errs.BadRequest{Err: err}
+	}
+	if jar == nil {
+		return []*http.Cookie{}, nil
+	}
+	return jar.Cookies(u), nil
+}
+
+// setCookiesByDomain stores cookies in jar honoring the Domain of each cookie
+// rather than the URL of the request they were received with, so cookies of
+// third-party domains (e.g. set during an OAuth flow) are kept and sent to
+// their domains. Cookies without Domain are set for fallback. The domains
+// are not checked against the URLs the cookies were received with, so it is
+// only used for cookies reported by a browser which already checked them.
+func setCookiesByDomain(jar http.CookieJar, cookies []*http.Cookie, fallback *url.URL) {
+	byURL := make(map[string][]*http.Cookie)
+	urls := make(map[string]*url.URL)
+	for _, c := range cookies {
+		u := fallback
+		if domain := strings.TrimPrefix(c.Domain, "."); domain != "" {
+			scheme := "http"
+			if c.Secure {
+				scheme = "https"
+			}
+			u = &url.URL{Scheme: scheme, Host: domain, Path: "/"}
+		}
+		key := u.String()
+		urls[key] = u
+		byURL[key] = append(byURL[key], c)
+	}
+	for key, cookies := range byURL {
+		jar.SetCookies(urls[key], cookies)
+	}
+}
+
+// setStoredCookies stores cookies listed by CookieJar.AllCookies in jar,
+// each for the URL it was originally set for, so the jar checks their
+// domains against that URL again. Cookies without URL are set for fallback
+// or dropped if it is nil.
+func setStoredCookies(jar http.CookieJar, cookies []StoredCookie, fallback *url.URL) {
+	var order []string
+	byURL := make(map[string][]*http.Cookie)
+	urls := make(map[string]*url.URL)
+	for i := range cookies {
+		u := fallback
+		if cookies[i].URL != "" {
+			var err error
+			if u, err = url.Parse(cookies[i].URL); err != nil {
+				continue
+			}
+		}
+		if u == nil {
+			continue
+		}
+		key := u.String()
+		if _, ok := urls[key]; !ok {
+			order = append(order, key)
+			urls[key] = u
+		}
+		byURL[key] = append(byURL[key], &cookies[i].Cookie)
+	}
+	for _, key := range order {
+		jar.SetCookies(urls[key], byURL[key])
+	}
+}
@@ -0,0 +1,371 @@
+package fetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mafredri/cdp/protocol/network"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/publicsuffix"
+)
+
+// mockJar records cookies it is given and sends them back to any URL.
+type mockJar struct {
+	cookies []*http.Cookie
+	urls    []string
+}
+
+func (j *mockJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.urls = append(j.urls, u.String())
+	j.cookies = append(j.cookies, cookies...)
+}
+
+func (j *mockJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.cookies
+}
+
+func (j *mockJar) AllCookies() []StoredCookie {
+	cookies := make([]StoredCookie, len(j.cookies))
+	for i, c := range j.cookies {
+		cookies[i] = StoredCookie{Cookie: *c}
+	}
+	return cookies
+}
+
+func TestBaseFetcher_CustomCookieJar(t *testing.T) {
+	var sent []*http.Cookie
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = r.Cookies()
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+	}))
+	defer ts.Close()
+
+	jar := &mockJar{}
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.setCookieJar(jar)
+	assert.Equal(t, jar, fetcher.getCookieJar())
+
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ts.URL}, jar.urls)
+	assert.Len(t, jar.AllCookies(), 1)
+
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, "session", sent[0].Name)
+		assert.Equal(t, "abc", sent[0].Value)
+	}
+}
+
+func TestNewCookieJar(t *testing.T) {
+	cJar, _ := cookiejar.New(nil)
+	jar := NewCookieJar(cJar)
+	u1, _ := url.Parse("http://example.com/")
+	u2, _ := url.Parse("http://example.org/")
+	jar.SetCookies(u1, []*http.Cookie{{Name: "c1", Value: "v1"}, {Name: "c2", Value: "v2"}})
+	jar.SetCookies(u2, []*http.Cookie{{Name: "c1", Value: "v3"}})
+	assert.Len(t, jar.Cookies(u1), 2)
+	assert.Len(t, jar.Cookies(u2), 1)
+	all := jar.AllCookies()
+	assert.Len(t, all, 3)
+	for _, c := range all {
+		//host-only cookies stay host-only
+		assert.Empty(t, c.Domain)
+		assert.Contains(t, []string{"http://example.com/", "http://example.org/"}, c.URL)
+	}
+	//deleted cookie
+	jar.SetCookies(u1, []*http.Cookie{{Name: "c2", MaxAge: -1}})
+	assert.Len(t, jar.Cookies(u1), 1)
+	assert.Len(t, jar.AllCookies(), 2)
+}
+
+func TestNewCookieJar_MaxAge(t *testing.T) {
+	cJar, _ := cookiejar.New(nil)
+	jar := NewCookieJar(cJar)
+	u, _ := url.Parse("http://example.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "1", MaxAge: 1}})
+	saved := jar.AllCookies()
+	if assert.Len(t, saved, 1) {
+		assert.Equal(t, 0, saved[0].MaxAge)
+		assert.WithinDuration(t, time.Now().Add(time.Second), saved[0].Expires, 100*time.Millisecond)
+	}
+	data, err := json.Marshal(saved)
+	assert.NoError(t, err)
+
+	//restoring after Max-Age elapsed doesn't revive the cookie
+	time.Sleep(1100 * time.Millisecond)
+	assert.Empty(t, jar.AllCookies())
+	var restored []StoredCookie
+	assert.NoError(t, json.Unmarshal(data, &restored))
+	cJar, _ = cookiejar.New(nil)
+	jar = NewCookieJar(cJar)
+	setStoredCookies(jar, restored, u)
+	assert.Empty(t, jar.Cookies(u))
+	assert.Empty(t, jar.AllCookies())
+}
+
+func TestNewCookieJar_Domains(t *testing.T) {
+	cJar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	jar := NewCookieJar(cJar)
+	evil, _ := url.Parse("http://evil.example/login")
+	jar.SetCookies(evil, []*http.Cookie{
+		{Name: "foreign", Value: "1", Domain: "bank.example"},
+		{Name: "suffix", Value: "1", Domain: "example"},
+		{Name: "malformed", Value: "1", Domain: "evil.example."},
+		{Name: "own", Value: "1", Domain: ".Evil.Example"},
+		{Name: "host", Value: "1"},
+	})
+	all := jar.AllCookies()
+	names := map[string]StoredCookie{}
+	for _, c := range all {
+		names[c.Name] = c
+	}
+	assert.Len(t, all, 2, "rejected cookies are not kept")
+	assert.Equal(t, "evil.example", names["own"].Domain)
+	assert.Equal(t, "", names["host"].Domain)
+	assert.Equal(t, "/", names["host"].Path)
+	assert.Equal(t, "http://evil.example/", names["host"].URL)
+
+	//replayed cookies are checked against their URL again
+	cJar, _ = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	restored := NewCookieJar(cJar)
+	setStoredCookies(restored, all, nil)
+	bank, _ := url.Parse("http://bank.example/")
+	assert.Empty(t, restored.Cookies(bank))
+	sub, _ := url.Parse("http://www.evil.example/")
+	if cookies := restored.Cookies(sub); assert.Len(t, cookies, 1) {
+		assert.Equal(t, "own", cookies[0].Name)
+	}
+	assert.Len(t, restored.Cookies(evil), 2)
+
+	//cookies of IP hosts are host-only
+	ip, _ := url.Parse("http://127.0.0.1:8080/a/b")
+	jar.SetCookies(ip, []*http.Cookie{{Name: "ip", Value: "1"}, {Name: "ipdomain", Value: "1", Domain: "127.0.0.1"}})
+	for _, c := range jar.AllCookies() {
+		if c.Name == "ip" {
+			assert.Equal(t, "/a", c.Path)
+			assert.Equal(t, "http://127.0.0.1:8080/", c.URL)
+		}
+		assert.NotEqual(t, "ipdomain", c.Name)
+	}
+}
+
+func TestFetcher_CookiesForURL(t *testing.T) {
+	var sent []*http.Cookie
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = r.Cookies()
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		http.SetCookie(w, &http.Cookie{Name: "admin", Value: "xyz", Path: "/admin"})
+	}))
+	defer ts.Close()
+
+	cJar, _ := cookiejar.New(nil)
+	fetcher, err := NewBaseFetcherWithOptions(WithCookieJar(NewCookieJar(cJar)))
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+
+	for _, path := range []string{"/", "/admin/users"} {
+		cookies, err := fetcher.CookiesForURL(ts.URL + path)
+		assert.NoError(t, err)
+		_, err = fetcher.Fetch(Request{URL: ts.URL + path})
+		assert.NoError(t, err)
+		assert.Equal(t, len(sent), len(cookies), path)
+		for i := range cookies {
+			assert.Equal(t, sent[i].Name, cookies[i].Name)
+			assert.Equal(t, sent[i].Value, cookies[i].Value)
+		}
+	}
+	_, err = fetcher.CookiesForURL("invalid_url")
+	assert.Error(t, err)
+
+	//fetcher without jar
+	chrome := newChromeFetcher()
+	cookies, err := chrome.CookiesForURL(ts.URL)
+	assert.NoError(t, err)
+	assert.Empty(t, cookies)
+}
+
+func TestSetCookiesByDomain(t *testing.T) {
+	cJar, _ := cookiejar.New(nil)
+	jar := NewCookieJar(cJar)
+	fallback, _ := url.Parse("http://app.example.com/login")
+	setCookiesByDomain(jar, []*http.Cookie{
+		{Name: "app", Value: "1"},
+		{Name: "sso", Value: "2", Domain: "auth.example.org"},
+		{Name: "sso_secure", Value: "3", Domain: ".auth.example.org", Secure: true},
+	}, fallback)
+
+	cookies, err := cookiesForURL(jar, "http://app.example.com/")
+	assert.NoError(t, err)
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, "app", cookies[0].Name)
+	}
+	cookies, err = cookiesForURL(jar, "http://auth.example.org/authorize")
+	assert.NoError(t, err)
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, "sso", cookies[0].Name)
+	}
+	cookies, err = cookiesForURL(jar, "https://auth.example.org/authorize")
+	assert.NoError(t, err)
+	assert.Len(t, cookies, 2)
+	assert.Len(t, jar.AllCookies(), 3)
+}
+
+func TestCookieJar_MalformedExpires(t *testing.T) {
+	expires := time.Date(2037, time.October, 21, 7, 28, 0, 0, time.UTC)
+	malformed := map[string]string{
+		"two-digit-year": "Wed, 21-Oct-37 07:28:00 GMT",
+		"rfc850":         "Wednesday, 21-Oct-37 07:28:00 GMT",
+		"numeric-zone":   "Wed, 21 Oct 2037 09:28:00 +0200",
+		"no-zone":        "Wed, 21 Oct 2037 07:28:00",
+		"no-weekday":     "21 Oct 2037 07:28:00 GMT",
+		"ansic":          "Wed Oct 21 07:28:00 2037",
+		"quoted":         `"Wed, 21 Oct 2037 07:28:00 GMT"`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, exp := range malformed {
+			w.Header().Add("Set-Cookie", name+"=1; Expires="+exp)
+		}
+		w.Header().Add("Set-Cookie", "valid=1; Expires=Wed, 21 Oct 2037 07:28:00 GMT")
+		w.Header().Add("Set-Cookie", "session=1")
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	fetchCookies := func(jar CookieJar) map[string]StoredCookie {
+		fetcher, err := NewBaseFetcherWithOptions(WithCookieJar(jar))
+		assert.NoError(t, err)
+		fetchAll(t, fetcher, ts.URL)
+		cookies := make(map[string]StoredCookie)
+		for _, c := range jar.AllCookies() {
+			cookies[c.Name] = c
+		}
+		return cookies
+	}
+
+	cJar, _ := cookiejar.New(nil)
+	cookies := fetchCookies(NewCookieJar(cJar))
+	assert.Len(t, cookies, len(malformed)+2)
+	for name := range malformed {
+		if assert.Contains(t, cookies, name) {
+			assert.True(t, expires.Equal(cookies[name].Expires), name)
+		}
+	}
+	assert.True(t, expires.Equal(cookies["valid"].Expires))
+	assert.True(t, cookies["session"].Expires.IsZero())
+
+	cJar, _ = cookiejar.New(nil)
+	cookies = fetchCookies(NewStrictCookieJar(cJar))
+	assert.Len(t, cookies, 2)
+	assert.Contains(t, cookies, "valid")
+	assert.Contains(t, cookies, "session")
+	u, _ := url.Parse(ts.URL)
+	assert.Len(t, cJar.Cookies(u), 2, "rejected cookies are not passed on to the jar")
+}
+
+func TestParseLenientExpires(t *testing.T) {
+	_, ok := parseLenientExpires("next tuesday")
+	assert.False(t, ok)
+	exp, ok := parseLenientExpires("Wed, 1-Oct-2037 07:28:00 GMT")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2037, time.October, 1, 7, 28, 0, 0, time.UTC), exp)
+}
+
+func TestSyncCookieJar(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "id", Value: r.URL.Query().Get("id")})
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	mock := &mockJar{}
+	jar := NewSyncCookieJar(mock)
+	fetcher, err := NewBaseFetcherWithOptions(WithCookieJar(jar))
+	assert.NoError(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				fetchAll(t, fetcher, fmt.Sprintf("%s/?id=%d-%d", ts.URL, i, j))
+				jar.AllCookies()
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, jar.AllCookies(), 200)
+	assert.Len(t, mock.urls, 200)
+}
+
+func TestBrowserCookies(t *testing.T) {
+	cJar, _ := cookiejar.New(nil)
+	jar := NewCookieJar(cJar)
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	page, _ := url.Parse("http://app.example.com/login")
+	//cookies as reported by Chrome after a login flow setting a cookie from JS
+	setCookiesByDomain(jar, browserCookies([]network.Cookie{
+		{Name: "session", Value: "1", Domain: "app.example.com", Path: "/", HTTPOnly: true, Session: true},
+		{Name: "js_token", Value: "2", Domain: ".example.com", Path: "/", Expires: float64(expires.Unix())},
+	}), page)
+
+	cookies, err := cookiesForURL(jar, "http://app.example.com/account")
+	assert.NoError(t, err)
+	assert.Len(t, cookies, 2)
+	for _, c := range jar.AllCookies() {
+		if c.Name == "js_token" {
+			assert.True(t, c.Expires.Equal(expires))
+		}
+	}
+}
+
+func TestCookiesJSON(t *testing.T) {
+	exported := `[
+  {
+    "name": "session",
+    "value": "abc",
+    "domain": "example.com",
+    "path": "/",
+    "expires": "2100-01-02T03:04:05Z",
+    "secure": true,
+    "httpOnly": true,
+    "sameSite": "Strict"
+  },
+  {
+    "name": "theme",
+    "value": "dark",
+    "domain": "example.com",
+    "path": "/app"
+  }
+]`
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	assert.NoError(t, ImportCookiesJSON(fetcher, []byte(exported)))
+	cookies, err := fetcher.CookiesForURL("https://example.com/app")
+	assert.NoError(t, err)
+	assert.Len(t, cookies, 2)
+
+	data, err := ExportCookiesJSON(fetcher)
+	assert.NoError(t, err)
+	assert.Equal(t, exported, string(data))
+
+	//cookies without domain can't be stored
+	assert.Error(t, ImportCookiesJSON(fetcher, []byte(`[{"name":"a","value":"b"}]`)))
+	assert.Error(t, ImportCookiesJSON(fetcher, []byte(`[{"name":"a","domain":"example.com","sameSite":"Loose"}]`)))
+
+	empty, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	data, err = ExportCookiesJSON(empty)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(data))
+}
@@ -0,0 +1,31 @@
+package fetch
+
+import "context"
+
+// DefaultCorrelationIDHeader is the header Request.CorrelationID is sent in
+// unless BaseFetcher.CorrelationIDHeader says otherwise.
+const DefaultCorrelationIDHeader = "X-Request-ID"
+
+// correlationIDKey is the request context key of Request.CorrelationID.
+type correlationIDKey struct{}
+
+// withCorrelationID returns a copy of ctx carrying the correlation ID id.
+func withCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns Request.CorrelationID of the fetch ctx belongs to or
+// an empty string. Contexts of requests sent by BaseFetcher carry it, so
+// RoundTripperMiddleware may use it, e.g. as a metrics label.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// correlationIDHeader returns the name of the header the correlation ID is sent in.
+func (bf *BaseFetcher) correlationIDHeader() string {
+	if bf.CorrelationIDHeader != "" {
+		return bf.CorrelationIDHeader
+	}
+	return DefaultCorrelationIDHeader
+}
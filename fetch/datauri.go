@@ -0,0 +1,76 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// defaultDataMediaType is the media type of data URIs not giving one, see RFC 2397.
+const defaultDataMediaType = "text/plain;charset=US-ASCII"
+
+// isDataURI reports whether rawurl is a data URI.
+func isDataURI(rawurl string) bool {
+	return len(rawurl) >= 5 && strings.EqualFold(rawurl[:5], "data:")
+}
+
+// dataResponse decodes the data URI
+// data:[<mediatype>][;base64],<data> into a synthetic 200 response with
+// the media type as Content-Type. A malformed URI returns errs.BadRequest.
+func dataResponse(rawurl string) (*http.Response, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, &errs.BadRequest{Err: err}
+	}
+	comma := strings.IndexByte(rawurl, ',')
+	if comma < 0 {
+		return nil, &errs.BadRequest{Err: errors.New("data URI without comma")}
+	}
+	mediaType, data := rawurl[5:comma], rawurl[comma+1:]
+	isBase64 := false
+	if strings.HasSuffix(strings.ToLower(mediaType), ";base64") {
+		isBase64 = true
+		mediaType = mediaType[:len(mediaType)-len(";base64")]
+	}
+	if mediaType == "" {
+		mediaType = defaultDataMediaType
+	} else if strings.HasPrefix(mediaType, ";") {
+		mediaType = "text/plain" + mediaType
+	}
+	if mediaType, err = url.PathUnescape(mediaType); err != nil {
+		return nil, &errs.BadRequest{Err: err}
+	}
+	unescaped, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, &errs.BadRequest{Err: err}
+	}
+	body := []byte(unescaped)
+	if isBase64 {
+		//whitespace is allowed in base64 data, padding may be left out
+		encoded := strings.TrimRight(strings.Join(strings.Fields(unescaped), ""), "=")
+		if body, err = base64.RawStdEncoding.DecodeString(encoded); err != nil {
+			return nil, &errs.BadRequest{Err: err}
+		}
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Content-Type":   {mediaType},
+			"Content-Length": {strconv.Itoa(len(body))},
+		},
+		ContentLength: int64(len(body)),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		Request:       &http.Request{Method: "GET", URL: u, Header: http.Header{}},
+	}, nil
+}
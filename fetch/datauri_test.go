@@ -0,0 +1,42 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_DataURI(t *testing.T) {
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	tests := []struct {
+		url         string
+		contentType string
+		body        string
+	}{
+		{"data:text/html;base64,PGgxPkhlbGxvPC9oMT4=", "text/html", "<h1>Hello</h1>"},
+		{"data:image/svg+xml;base64,PHN2Zy8+", "image/svg+xml", "<svg/>"},
+		{"data:,Hello%2C%20World/", "text/plain;charset=US-ASCII", "Hello, World/"},
+		{"data:text/plain;charset=utf-8,caf%C3%A9", "text/plain;charset=utf-8", "café"},
+		{"DATA:;charset=utf-8;base64,Y2Fm w6k", "text/plain;charset=utf-8", "café"},
+	}
+	for _, tt := range tests {
+		resp, err := fetcher.FetchResponse(Request{URL: tt.url})
+		if !assert.NoError(t, err, tt.url) {
+			continue
+		}
+		assert.Equal(t, 200, resp.GetStatusCode(), tt.url)
+		assert.Equal(t, tt.contentType, resp.GetHeaders().Get("Content-Type"), tt.url)
+		body, err := ioutil.ReadAll(resp)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.body, string(body), tt.url)
+		resp.Close()
+	}
+
+	_, err = fetcher.FetchResponse(Request{URL: "data:text/plain;base64"})
+	assert.IsType(t, &errs.BadRequest{}, err)
+	_, err = fetcher.FetchResponse(Request{URL: "data:;base64,!!!"})
+	assert.IsType(t, &errs.BadRequest{}, err)
+}
@@ -0,0 +1,86 @@
+package fetch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// dumpName returns the base name of the dump files of the next fetch. Names
+// start with the time the request is sent so they sort in fetch order and
+// dumps of several runs don't collide.
+func (bf *BaseFetcher) dumpName() string {
+	seq := atomic.AddUint32(&bf.dumpSeq, 1)
+	return fmt.Sprintf("%s-%06d", time.Now().Format("20060102T150405.000000"), seq)
+}
+
+// dumpRequest writes req with its body to a .request file in DebugDumpDir.
+// It returns the path the dump of the response is to be written to, or an
+// empty string if the dump fails. Dump failures are logged and don't fail
+// the fetch.
+func (bf *BaseFetcher) dumpRequest(req *http.Request) string {
+	if err := os.MkdirAll(bf.DebugDumpDir, 0755); err != nil {
+		logger.Error(err)
+		return ""
+	}
+	dump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		logger.Error(err)
+		return ""
+	}
+	base := filepath.Join(bf.DebugDumpDir, bf.dumpName())
+	if err := ioutil.WriteFile(base+".request", dump, 0644); err != nil {
+		logger.Error(err)
+		return ""
+	}
+	return base + ".response"
+}
+
+// dumpResponse writes the headers of resp to the file path and copies the
+// body there as it is read. The body is written decoded, its headers are
+// dumped after Content-Encoding is removed so both match.
+func dumpResponse(path string, resp *http.Response) {
+	dump, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	if _, err := f.Write(dump); err != nil {
+		logger.Error(err)
+		f.Close()
+		return
+	}
+	resp.Body = &dumpingBody{ReadCloser: resp.Body, file: f}
+}
+
+// dumpingBody copies the body read to file, which is closed with the body.
+type dumpingBody struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (b *dumpingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if _, werr := b.file.Write(p[:n]); werr != nil {
+			logger.Error(werr)
+		}
+	}
+	return n, err
+}
+
+func (b *dumpingBody) Close() error {
+	b.file.Close()
+	return b.ReadCloser.Close()
+}
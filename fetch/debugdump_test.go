@@ -0,0 +1,60 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_DebugDumpDir(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "dump")
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "dump")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fetcher, err := NewBaseFetcherWithOptions(WithDebugDumpDir(filepath.Join(dir, "fetches")))
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(Request{URL: ts.URL, FormData: "a=b"})
+	assert.NoError(t, err)
+	ioutil.ReadAll(content)
+	content.Close()
+
+	req, err := filepath.Glob(filepath.Join(dir, "fetches", "*.request"))
+	assert.NoError(t, err)
+	assert.Len(t, req, 1)
+	dump, _ := ioutil.ReadFile(req[0])
+	assert.True(t, strings.HasPrefix(string(dump), "POST / HTTP/1.1"), string(dump))
+	assert.True(t, strings.HasSuffix(string(dump), "a=b"), string(dump))
+	dump, _ = ioutil.ReadFile(strings.TrimSuffix(req[0], ".request") + ".response")
+	assert.Contains(t, string(dump), "200 OK")
+	assert.Contains(t, string(dump), "X-Test: dump")
+	assert.True(t, strings.HasSuffix(string(dump), string(helloContent)), string(dump))
+
+	//concurrent fetches are written to files of their own
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			content, err := fetcher.Fetch(Request{URL: ts.URL})
+			assert.NoError(t, err)
+			ioutil.ReadAll(content)
+			content.Close()
+		}()
+	}
+	wg.Wait()
+	files, err := ioutil.ReadDir(filepath.Join(dir, "fetches"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 22)
+}
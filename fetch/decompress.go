@@ -0,0 +1,143 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// gzipMagic are the first bytes of a gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// sniffGzip decompresses the body of resp if it starts with gzip magic bytes
+// although the server didn't declare Content-Encoding.
+func sniffGzip(resp *http.Response) error {
+	if resp.Uncompressed || resp.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+	br := bufio.NewReader(resp.Body)
+	head, _ := br.Peek(len(gzipMagic))
+	if !bytes.Equal(head, gzipMagic) {
+		resp.Body = readCloser{br, resp.Body}
+		return nil
+	}
+	return gunzip(resp, br)
+}
+
+// gzipBytes returns data gzip compressed.
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gunzip replaces the body of resp with the decompressed gzip stream read from r.
+func gunzip(resp *http.Response, r io.Reader) error {
+	zr, err := gzip.NewReader(r)
+	if err == io.EOF {
+		//nothing to decompress, e.g. HEAD response
+		return nil
+	}
+	if err != nil {
+		resp.Body.Close()
+		return err
+	}
+	//servers may send a concatenation of gzip members, all of them are decoded
+	zr.Multistream(true)
+	resp.Body = readCloser{zr, resp.Body}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.Uncompressed = true
+	return nil
+}
+
+// ContentTypes returns KeepCompressed predicate matching responses with any of
+// the given media types, e.g. "application/gzip".
+func ContentTypes(types ...string) func(*http.Response) bool {
+	return func(resp *http.Response) bool {
+		mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if err != nil {
+			return false
+		}
+		for _, t := range types {
+			if strings.EqualFold(t, mediaType) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// decodedBody is a decoded response body closing the decoder along with
+// the body it reads from.
+type decodedBody struct {
+	io.Reader
+	decoder io.Closer
+	body    io.Closer
+}
+
+func (b decodedBody) Close() error {
+	b.decoder.Close()
+	return b.body.Close()
+}
+
+// decompress decodes the gzip or ContentDecoders encoded body of resp unless
+// KeepCompressed reports it has to be passed through untouched. BaseFetcher
+// decodes bodies itself instead of leaving it to http.Transport, so it is able to pass
+// them through and to count the bytes received, see Response.GetWireBytes.
+func (bf *BaseFetcher) decompress(resp *http.Response) error {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	decoder, ok := bf.ContentDecoders[encoding]
+	if encoding != "gzip" && !ok {
+		return nil
+	}
+	if bf.KeepCompressed != nil && bf.KeepCompressed(resp) {
+		return nil
+	}
+	if encoding == "gzip" {
+		return gunzip(resp, resp.Body)
+	}
+	r, err := decoder(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return err
+	}
+	if closer, ok := r.(io.Closer); ok {
+		resp.Body = decodedBody{r, closer, resp.Body}
+	} else {
+		resp.Body = readCloser{r, resp.Body}
+	}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.Uncompressed = true
+	return nil
+}
+
+// acceptEncoding returns the Accept-Encoding header listing gzip and, if
+// AdvertiseContentDecoders is set, the encodings of ContentDecoders.
+func (bf *BaseFetcher) acceptEncoding() string {
+	encodings := []string{}
+	if !bf.AdvertiseContentDecoders {
+		return "gzip"
+	}
+	for encoding := range bf.ContentDecoders {
+		if encoding != "gzip" {
+			encodings = append(encodings, encoding)
+		}
+	}
+	sort.Strings(encodings)
+	return strings.Join(append([]string{"gzip"}, encodings...), ", ")
+}
@@ -0,0 +1,218 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipData(data []byte) []byte {
+	compressed, _ := gzipBytes(data)
+	return compressed
+}
+
+func fetchAll(t *testing.T, fetcher *BaseFetcher, url string) []byte {
+	content, err := fetcher.Fetch(Request{URL: url})
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	defer content.Close()
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	return data
+}
+
+func TestBaseFetcher_SniffGzip(t *testing.T) {
+	compressed := gzipData(helloContent)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/undeclared":
+			w.Write(compressed)
+		case "/short":
+			w.Write([]byte{0x1f})
+		default:
+			w.Write(helloContent)
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, compressed, fetchAll(t, fetcher, ts.URL+"/undeclared"), "off by default")
+
+	fetcher.SniffGzip = true
+	assert.Equal(t, helloContent, fetchAll(t, fetcher, ts.URL+"/undeclared"))
+	assert.Equal(t, helloContent, fetchAll(t, fetcher, ts.URL+"/plain"))
+	assert.Equal(t, []byte{0x1f}, fetchAll(t, fetcher, ts.URL+"/short"))
+}
+
+func TestBaseFetcher_KeepCompressed(t *testing.T) {
+	archive := gzipData([]byte("archived data"))
+	page := gzipData(helloContent)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "gzip")
+		if r.URL.Path == "/archive.gz" {
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Write(archive)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.KeepCompressed = ContentTypes("application/gzip", "application/x-gzip")
+	assert.Equal(t, archive, fetchAll(t, fetcher, ts.URL+"/archive.gz"))
+	assert.Equal(t, helloContent, fetchAll(t, fetcher, ts.URL+"/index.html"))
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL + "/archive.gz"})
+	assert.NoError(t, err)
+	assert.Equal(t, "gzip", resp.GetHeaders().Get("Content-Encoding"))
+	resp, err = fetcher.FetchResponse(Request{URL: ts.URL + "/index.html"})
+	assert.NoError(t, err)
+	assert.Equal(t, "", resp.GetHeaders().Get("Content-Encoding"))
+	//HEAD response has no body to decompress
+	_, err = fetcher.Fetch(Request{URL: ts.URL, Method: "HEAD"})
+	assert.NoError(t, err)
+}
+
+func TestBaseFetcher_GzipBody(t *testing.T) {
+	value := strings.Repeat("dataflowkit", 10000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		assert.True(t, r.ContentLength > 0 && r.ContentLength < int64(len(value)), "compressed length is sent")
+		zr, err := gzip.NewReader(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		body, err := ioutil.ReadAll(zr)
+		assert.NoError(t, err)
+		form, err := url.ParseQuery(string(body))
+		assert.NoError(t, err)
+		assert.Equal(t, value, form.Get("data"))
+		assert.Equal(t, "1", form.Get("n"))
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	resp, err := fetcher.Fetch(Request{URL: ts.URL, FormData: "data=" + value + "&n=1", GzipBody: true})
+	if assert.NoError(t, err) {
+		resp.Close()
+	}
+}
+
+func TestBaseFetcher_GzipMultistream(t *testing.T) {
+	members := append(gzipData([]byte("first member, ")), gzipData([]byte("second member"))...)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sniffed" {
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+		w.Write(members)
+	}))
+	defer ts.Close()
+
+	expected := []byte("first member, second member")
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, fetchAll(t, fetcher, ts.URL))
+	fetcher.KeepCompressed = ContentTypes("application/gzip")
+	assert.Equal(t, expected, fetchAll(t, fetcher, ts.URL))
+	fetcher.SniffGzip = true
+	assert.Equal(t, expected, fetchAll(t, fetcher, ts.URL+"/sniffed"))
+}
+
+func TestBaseFetcher_ContentDecoders(t *testing.T) {
+	var buf bytes.Buffer
+	zw, _ := flate.NewWriter(&buf, flate.BestCompression)
+	zw.Write(helloContent)
+	zw.Close()
+	deflated := buf.Bytes()
+	var acceptEncoding string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "deflate")
+		if r.URL.Path == "/broken" {
+			w.Write([]byte("not deflated"))
+			return
+		}
+		w.Write(deflated)
+	}))
+	defer ts.Close()
+
+	//deflate stands in for encodings like zstd decoded by third party packages
+	fetcher, err := NewBaseFetcherWithOptions(WithContentDecoder("Deflate", func(r io.Reader) (io.Reader, error) {
+		return flate.NewReader(r), nil
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, helloContent, fetchAll(t, fetcher, ts.URL))
+	assert.Equal(t, "gzip", acceptEncoding, "decoders are not advertised by default")
+	fetcher.AdvertiseContentDecoders = true
+	assert.Equal(t, helloContent, fetchAll(t, fetcher, ts.URL))
+	assert.Equal(t, "gzip, deflate", acceptEncoding)
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, "", resp.GetHeaders().Get("Content-Encoding"))
+	resp.Close()
+
+	content, err := fetcher.Fetch(Request{URL: ts.URL + "/broken"})
+	if assert.NoError(t, err) {
+		_, err = ioutil.ReadAll(content)
+		assert.Error(t, err)
+		content.Close()
+	}
+	failing, err := NewBaseFetcherWithOptions(WithContentDecoder("deflate", func(r io.Reader) (io.Reader, error) {
+		return nil, errors.New("bad header")
+	}))
+	assert.NoError(t, err)
+	_, err = failing.Fetch(Request{URL: ts.URL})
+	if assert.IsType(t, &errs.BadGateway{}, err) {
+		assert.Equal(t, "deflate content", err.(*errs.BadGateway).What)
+	}
+
+	fetcher.KeepCompressed = func(*http.Response) bool { return true }
+	assert.Equal(t, deflated, fetchAll(t, fetcher, ts.URL))
+}
+
+type closingDecoder struct {
+	io.Reader
+	closed bool
+}
+
+func (d *closingDecoder) Close() error {
+	d.closed = true
+	return nil
+}
+
+func TestBaseFetcher_ContentDecoderClose(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "zstd")
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	//decoders like zstd's hold resources until they are closed
+	decoder := &closingDecoder{}
+	fetcher, err := NewBaseFetcherWithOptions(WithContentDecoder("zstd", func(r io.Reader) (io.Reader, error) {
+		decoder.Reader = r
+		return decoder, nil
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, helloContent, fetchAll(t, fetcher, ts.URL))
+	assert.True(t, decoder.closed)
+}
@@ -0,0 +1,59 @@
+package fetch
+
+import (
+	"container/list"
+	"sync"
+)
+
+// ContentHashes is the set of content hashes of the responses seen during a
+// crawl, used to recognize documents served at several URLs, e.g. by mirrors
+// or with session IDs in the URL, see Response.Duplicate. The least recently
+// seen hash is evicted once there are MaxEntries of them. ContentHashes may
+// be shared by several fetchers.
+//
+// ContentHashes is safe for concurrent use.
+type ContentHashes struct {
+	// MaxEntries is the maximum number of hashes kept. Zero means no limit.
+	MaxEntries int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// NewContentHashes returns an empty ContentHashes keeping up to maxEntries hashes.
+func NewContentHashes(maxEntries int) *ContentHashes {
+	return &ContentHashes{
+		MaxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Len returns the number of hashes kept.
+func (c *ContentHashes) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Seen adds hash to the set and reports whether it was in the set already.
+func (c *ContentHashes) Seen(hash string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.items == nil {
+		c.ll = list.New()
+		c.items = make(map[string]*list.Element)
+	}
+	if el, ok := c.items[hash]; ok {
+		c.ll.MoveToFront(el)
+		return true
+	}
+	c.items[hash] = c.ll.PushFront(hash)
+	if c.MaxEntries > 0 && c.ll.Len() > c.MaxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(string))
+	}
+	return false
+}
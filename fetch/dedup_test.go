@@ -0,0 +1,66 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_ContentHashes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/other" {
+			w.Write([]byte("other content"))
+			return
+		}
+		//mirrors and session ID URLs serve the same document
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	hashes := NewContentHashes(10)
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.ContentHashes = hashes
+	for _, tt := range []struct {
+		url       string
+		duplicate bool
+	}{
+		{ts.URL + "/page", false},
+		{ts.URL + "/other", false},
+		{ts.URL + "/mirror/page", true},
+		{ts.URL + "/page?sid=123", true},
+	} {
+		resp, err := fetcher.FetchResponse(Request{URL: tt.url})
+		assert.NoError(t, err)
+		assert.Equal(t, tt.duplicate, resp.Duplicate(), tt.url)
+		assert.Equal(t, tt.duplicate, resp.Duplicate(), tt.url)
+		//the body is still there to be read
+		body, err := ioutil.ReadAll(resp)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, body)
+		resp.Close()
+	}
+	assert.Equal(t, 2, hashes.Len())
+
+	//without ContentHashes nothing is a duplicate
+	fetcher.ContentHashes = nil
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL + "/page"})
+	assert.NoError(t, err)
+	assert.False(t, resp.Duplicate())
+	resp.Close()
+}
+
+func TestContentHashes(t *testing.T) {
+	c := NewContentHashes(2)
+	assert.False(t, c.Seen("a"))
+	assert.False(t, c.Seen("b"))
+	assert.True(t, c.Seen("a"))
+	//b is the least recently seen one
+	assert.False(t, c.Seen("c"))
+	assert.Equal(t, 2, c.Len())
+	assert.True(t, c.Seen("a"))
+	assert.False(t, c.Seen("b"))
+}
@@ -0,0 +1,113 @@
+package fetch
+
+import (
+	"context"
+	"net"
+)
+
+// dialContext connects to the address on the named network with dial.
+// Connections are recycled after MaxConnLifetime if it is set.
+func (bf *BaseFetcher) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := bf.dial(ctx, network, addr)
+	if err != nil || bf.MaxConnLifetime <= 0 {
+		return conn, err
+	}
+	return newLifetimeConn(conn, bf.MaxConnLifetime, &bf.lifetimeConns), nil
+}
+
+// dial connects to the address on the named network. If UnixSocket
+// is set all connections are made to the socket. If the host of addr has an
+// entry in ResolveOverride the connection is made to the mapped address
+// instead. TCP connections are bound to LocalAddr if it is set and limited
+// by DialTimeout. Host restrictions are not applied to the proxy of
+// BaseFetcher, which is set by the operator, but to the proxy of
+// Request.ProxyURL.
+func (bf *BaseFetcher) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if bf.UnixSocket != "" {
+		return bf.dialer.DialContext(ctx, "unix", bf.UnixSocket)
+	}
+	host := addr
+	if override, ok := bf.resolveOverride(addr); ok {
+		addr = override
+	}
+	trustedProxy := bf.transport.Proxy != nil && ctx.Value(proxyKey{}) == nil
+	if bf.filtersHosts() && !trustedProxy {
+		return bf.dialChecked(ctx, network, host, addr)
+	}
+	return bf.tcpDialer().DialContext(ctx, network, addr)
+}
+
+// tcpDialer returns the dialer of TCP connections bound to LocalAddr and
+// limited by DialTimeout.
+func (bf *BaseFetcher) tcpDialer() *net.Dialer {
+	if bf.LocalAddr == nil && bf.DialTimeout == 0 {
+		return bf.dialer
+	}
+	d := *bf.dialer
+	d.LocalAddr = bf.LocalAddr
+	if bf.DialTimeout > 0 {
+		d.Timeout = bf.DialTimeout
+	}
+	return &d
+}
+
+// dialChecked resolves addr itself and connects to the first of its IP
+// addresses allowed by host restrictions. Dialing the checked address
+// directly prevents the host from being rebound to another address between
+// the check and the connection. host is the address of the request before
+// ResolveOverride is applied.
+func (bf *BaseFetcher) dialChecked(ctx context.Context, network, host, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(host)
+	if err != nil {
+		return nil, err
+	}
+	ipHost, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, ipHost)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: ipHost}
+	}
+	for _, ip := range ips {
+		if err = bf.checkIP(host, ip.IP); err != nil {
+			return nil, err
+		}
+	}
+	dialer := bf.tcpDialer()
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// resolveOverride looks up addr in ResolveOverride. An exact host:port entry
+// takes precedence over a host one. If the mapped value carries no port the
+// port of addr is kept.
+func (bf *BaseFetcher) resolveOverride(addr string) (string, bool) {
+	if len(bf.ResolveOverride) == 0 {
+		return "", false
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", false
+	}
+	override, ok := bf.ResolveOverride[addr]
+	if !ok {
+		override, ok = bf.ResolveOverride[host]
+		if !ok {
+			return "", false
+		}
+	}
+	if _, _, err := net.SplitHostPort(override); err != nil {
+		override = net.JoinHostPort(override, port)
+	}
+	return override, true
+}
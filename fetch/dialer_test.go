@@ -0,0 +1,189 @@
+package fetch
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_ResolveOverride(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//original Host header should be kept
+		assert.Equal(t, "example.com", r.Host)
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	fetcher := newBaseFetcher()
+	fetcher.ResolveOverride = map[string]string{
+		"example.com": ts.Listener.Addr().String(),
+	}
+	content, err := fetcher.Fetch(Request{
+		URL:    "http://example.com/hello",
+		Method: "GET",
+	})
+	assert.NoError(t, err, "Expected no error")
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err, "Expected no error")
+	assert.Equal(t, helloContent, data)
+
+	fetcher.ResolveOverride = map[string]string{
+		"example.com:80": "127.0.0.1",
+	}
+	override, ok := fetcher.resolveOverride("example.com:80")
+	assert.True(t, ok)
+	assert.Equal(t, "127.0.0.1:80", override)
+	_, ok = fetcher.resolveOverride("example.org:80")
+	assert.False(t, ok)
+}
+
+func TestBaseFetcher_UnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dfk")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "fetch.sock")
+	l, err := net.Listen("unix", socket)
+	assert.NoError(t, err)
+	defer l.Close()
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "service.local", r.Host)
+		w.Write(helloContent)
+	}))
+
+	fetcher, err := NewBaseFetcherWithOptions(WithUnixSocket(socket))
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(Request{URL: "http://service.local/hello"})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, helloContent, data)
+}
+
+func TestBaseFetcher_LocalAddr(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		w.Write([]byte(host))
+	}))
+	defer ts.Close()
+
+	_, err := NewBaseFetcherWithOptions(WithLocalAddr("not an ip"))
+	assert.Error(t, err)
+
+	//any 127.0.0.0/8 address is a loopback alias on Linux
+	fetcher, err := NewBaseFetcherWithOptions(WithLocalAddr("127.0.0.2"))
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(Request{URL: ts.URL})
+	if err != nil {
+		t.Skipf("cannot bind to 127.0.0.2: %s", err)
+	}
+	defer content.Close()
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.2", string(data))
+}
+
+func TestBaseFetcher_ConnectionTimeouts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	//dialing times out
+	fetcher, err := NewBaseFetcherWithOptions(WithDialTimeout(time.Nanosecond))
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.IsType(t, &errs.ConnectionTimeout{}, err)
+
+	//the server accepts connections but never answers the TLS handshake
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	fetcher, err = NewBaseFetcherWithOptions(WithTLSHandshakeTimeout(50 * time.Millisecond))
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: "https://" + ln.Addr().String()})
+	assert.IsType(t, &errs.ConnectionTimeout{}, err)
+
+	//the server is connected but too slow to answer
+	fetcher, err = NewBaseFetcherWithOptions(
+		WithDialTimeout(time.Second),
+		WithTimeout(50*time.Millisecond),
+	)
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.IsType(t, &errs.GatewayTimeout{}, err)
+}
+
+func TestBaseFetcher_MaxConnLifetime(t *testing.T) {
+	for _, useTLS := range []bool{false, true} {
+		var dialed int32
+		ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				for i := 0; i < 4; i++ {
+					w.Write([]byte("chunk "))
+					w.(http.Flusher).Flush()
+					time.Sleep(25 * time.Millisecond)
+				}
+				return
+			}
+			w.Write([]byte("ok"))
+		}))
+		ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt32(&dialed, 1)
+			}
+		}
+		fetcher, err := NewBaseFetcherWithOptions()
+		assert.NoError(t, err)
+		if useTLS {
+			ts.StartTLS()
+			roots := x509.NewCertPool()
+			roots.AddCert(ts.Certificate())
+			fetcher.transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+		} else {
+			ts.Start()
+		}
+		fetcher.MaxConnLifetime = 50 * time.Millisecond
+		fetch := func(path string) string {
+			content, err := fetcher.Fetch(Request{URL: ts.URL + path})
+			if !assert.NoError(t, err, ts.URL) {
+				return ""
+			}
+			defer content.Close()
+			data, err := ioutil.ReadAll(content)
+			assert.NoError(t, err, ts.URL)
+			return string(data)
+		}
+		fetch("/")
+		fetch("/")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&dialed), "the connection is reused")
+		time.Sleep(80 * time.Millisecond)
+		fetch("/")
+		assert.Equal(t, int32(2), atomic.LoadInt32(&dialed), "the expired connection is recycled")
+
+		//a request in progress finishes on its expiring connection
+		assert.Equal(t, "chunk chunk chunk chunk ", fetch("/slow"), ts.URL)
+		fetch("/")
+		assert.Equal(t, int32(3), atomic.LoadInt32(&dialed), ts.URL)
+		ts.Close()
+	}
+}
@@ -0,0 +1,57 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DispatchFetcher sends each request to the fetcher of its Request.Type, so
+// a crawl can fetch most pages with BaseFetcher and JS driven pages with
+// ChromeFetcher through one Fetcher. Requests of type "chrome" go to the
+// Chrome fetcher, all others to the Base fetcher. Both fetchers share one
+// cookie jar.
+type DispatchFetcher struct {
+	base   Fetcher
+	chrome Fetcher
+}
+
+// NewDispatchFetcher returns DispatchFetcher dispatching to base and chrome.
+// The cookie jar of base, if any, is set to chrome too.
+func NewDispatchFetcher(base, chrome Fetcher) *DispatchFetcher {
+	f := &DispatchFetcher{base: base, chrome: chrome}
+	if jar := base.getCookieJar(); jar != nil {
+		chrome.setCookieJar(jar)
+	}
+	return f
+}
+
+// fetcher returns the fetcher for request.
+func (f *DispatchFetcher) fetcher(request Request) Fetcher {
+	if strings.EqualFold(request.Type, string(Chrome)) {
+		return f.chrome
+	}
+	return f.base
+}
+
+// Fetch fetches request with the fetcher of its type.
+func (f *DispatchFetcher) Fetch(request Request) (io.ReadCloser, error) {
+	return f.fetcher(request).Fetch(request)
+}
+
+// CookiesForURL returns the cookies of the shared cookie jar to be sent to the URL.
+func (f *DispatchFetcher) CookiesForURL(u string) ([]*http.Cookie, error) {
+	return f.base.CookiesForURL(u)
+}
+
+func (f *DispatchFetcher) getCookieJar() CookieJar {
+	return f.base.getCookieJar()
+}
+
+func (f *DispatchFetcher) setCookieJar(jar CookieJar) {
+	f.base.setCookieJar(jar)
+	f.chrome.setCookieJar(jar)
+}
+
+// Static type assertion
+var _ Fetcher = &DispatchFetcher{}
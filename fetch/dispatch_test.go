@@ -0,0 +1,66 @@
+package fetch
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//namedFetcher returns its name as content of every request.
+type namedFetcher struct {
+	name string
+	jar  CookieJar
+	urls []string
+}
+
+func (f *namedFetcher) Fetch(request Request) (io.ReadCloser, error) {
+	f.urls = append(f.urls, request.URL)
+	return ioutil.NopCloser(strings.NewReader(f.name)), nil
+}
+
+func (f *namedFetcher) CookiesForURL(u string) ([]*http.Cookie, error) {
+	return cookiesForURL(f.jar, u)
+}
+
+func (f *namedFetcher) getCookieJar() CookieJar { return f.jar }
+
+func (f *namedFetcher) setCookieJar(jar CookieJar) { f.jar = jar }
+
+func TestDispatchFetcher(t *testing.T) {
+	cJar, _ := cookiejar.New(nil)
+	jar := NewCookieJar(cJar)
+	base := &namedFetcher{name: "base", jar: jar}
+	chrome := &namedFetcher{name: "chrome"}
+	f := NewDispatchFetcher(base, chrome)
+	assert.Equal(t, jar, chrome.jar, "fetchers share the cookie jar")
+
+	for _, r := range []Request{
+		{URL: "http://example.com/1"},
+		{URL: "http://example.com/2", Type: "chrome"},
+		{URL: "http://example.com/3", Type: "Base"},
+		{URL: "http://example.com/4", Type: "Chrome"},
+	} {
+		content, err := f.Fetch(r)
+		assert.NoError(t, err)
+		data, _ := ioutil.ReadAll(content)
+		expected := "base"
+		if r.Type == "chrome" || r.Type == "Chrome" {
+			expected = "chrome"
+		}
+		assert.Equal(t, expected, string(data), r.URL)
+	}
+	assert.Equal(t, []string{"http://example.com/1", "http://example.com/3"}, base.urls)
+	assert.Equal(t, []string{"http://example.com/2", "http://example.com/4"}, chrome.urls)
+
+	cJar, _ = cookiejar.New(nil)
+	other := NewCookieJar(cJar)
+	f.setCookieJar(other)
+	assert.Equal(t, other, base.jar)
+	assert.Equal(t, other, chrome.jar)
+	assert.Equal(t, other, f.getCookieJar())
+}
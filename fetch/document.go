@@ -0,0 +1,52 @@
+package fetch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/errs"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+)
+
+// FetchDocument fetches an HTML document and parses it with goquery. The body
+// is converted to UTF-8 according to the charset given by Content-Type header
+// or <meta> tag, or to request.ForceCharset if set, before parsing. An
+// unknown ForceCharset returns errs.BadRequest. The returned Response is already read, it
+// keeps the response metadata. Errors fetching the document are returned as
+// by FetchResponse, a body which can't be parsed returns errs.BadDocument.
+func (bf *BaseFetcher) FetchDocument(request Request) (*goquery.Document, *Response, error) {
+	var forced encoding.Encoding
+	if request.ForceCharset != "" {
+		if forced, _ = charset.Lookup(request.ForceCharset); forced == nil {
+			return nil, nil, &errs.BadRequest{Err: fmt.Errorf("unknown charset %q", request.ForceCharset)}
+		}
+	}
+	resp, err := bf.FetchResponse(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Close()
+	body, err := ioutil.ReadAll(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	var utf8 io.Reader
+	if forced != nil {
+		utf8 = forced.NewDecoder().Reader(bytes.NewReader(body))
+	} else {
+		utf8, err = charset.NewReader(bytes.NewReader(body), resp.GetHeaders().Get("Content-Type"))
+		if err != nil {
+			return nil, nil, &errs.BadDocument{URL: resp.GetURL(), Err: err}
+		}
+	}
+	doc, err := goquery.NewDocumentFromReader(utf8)
+	if err != nil {
+		return nil, nil, &errs.BadDocument{URL: resp.GetURL(), Err: err}
+	}
+	doc.Url = resp.resp.Request.URL
+	return doc, resp, nil
+}
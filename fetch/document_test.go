@@ -0,0 +1,57 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_FetchDocument(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			w.Write([]byte("<html><body><h1>Caf\xe9</h1><ul><li>one</li><li>two</li></ul></body></html>"))
+		case "/meta":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><meta charset=\"windows-1251\"></head><body><h1>\xcf\xf0\xe8\xe2\xe5\xf2</h1></body></html>"))
+		case "/lying":
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			w.Write([]byte("<html><body><h1>\xcf\xf0\xe8\xe2\xe5\xf2</h1></body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+
+	doc, resp, err := fetcher.FetchDocument(Request{URL: ts.URL + "/latin1"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Café", doc.Find("h1").Text())
+		assert.Equal(t, 2, doc.Find("ul li").Length())
+		assert.Equal(t, "two", doc.Find("li").Last().Text())
+		assert.Equal(t, ts.URL+"/latin1", doc.Url.String())
+		assert.Equal(t, 200, resp.GetStatusCode())
+	}
+
+	doc, _, err = fetcher.FetchDocument(Request{URL: ts.URL + "/meta"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Привет", doc.Find("h1").Text())
+	}
+
+	//the declared charset is wrong, the page is windows-1251
+	doc, _, err = fetcher.FetchDocument(Request{URL: ts.URL + "/lying", ForceCharset: "windows-1251"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Привет", doc.Find("h1").Text())
+	}
+	_, _, err = fetcher.FetchDocument(Request{URL: ts.URL + "/lying", ForceCharset: "klingon"})
+	assert.IsType(t, &errs.BadRequest{}, err)
+
+	_, _, err = fetcher.FetchDocument(Request{URL: ts.URL + "/missing"})
+	assert.IsType(t, &errs.NotFound{}, err)
+}
@@ -0,0 +1,142 @@
+package fetch
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultEventRetry is how long FetchEvents waits before reconnecting
+// unless the server sets a retry time.
+const defaultEventRetry = 3 * time.Second
+
+// SSEvent is an event of a Server-Sent Events stream.
+type SSEvent struct {
+	// ID is the last event ID set by the stream, it may belong to an
+	// earlier event.
+	ID string
+	// Event is the event type, "message" unless set by the stream.
+	Event string
+	// Data is the event data, lines of multi-line data are joined with "\n".
+	Data string
+}
+
+// eventStream parses a text/event-stream body.
+type eventStream struct {
+	r *bufio.Reader
+	// lastID is the ID set by the stream up to the last complete event,
+	// id the one set so far.
+	lastID string
+	id     string
+	retry  time.Duration
+}
+
+// next returns the next event of the stream, io.EOF at its end or
+// io.ErrUnexpectedEOF if the stream ends in the middle of a line. An event
+// cut off by the end of the stream is dropped.
+func (s *eventStream) next() (SSEvent, error) {
+	var data []string
+	event := ""
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line != "" {
+				err = io.ErrUnexpectedEOF
+			}
+			return SSEvent{}, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if line == "" {
+			s.lastID = s.id
+			if data == nil {
+				event = ""
+				continue
+			}
+			if event == "" {
+				event = "message"
+			}
+			return SSEvent{ID: s.lastID, Event: event, Data: strings.Join(data, "\n")}, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "event":
+			event = value
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.id = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// FetchEvents fetches a Server-Sent Events stream and calls fn with each
+// event as it arrives. Accept is sent as text/event-stream unless set in
+// request.Headers. If the stream ends or breaks, FetchEvents reconnects up
+// to EventReconnects times, after the retry time sent by the server or 3
+// seconds, sending the ID of the last event in Last-Event-ID. A failed
+// reconnection returns its error. Reading stops at the first error
+// returned by fn, which is returned along with the last Response. The
+// returned Response is read and closed, it keeps the response metadata.
+func (bf *BaseFetcher) FetchEvents(request Request, fn func(event SSEvent) error) (*Response, error) {
+	headers := http.Header{}
+	for name, values := range request.Headers {
+		headers[name] = values
+	}
+	if headers.Get("Accept") == "" {
+		headers.Set("Accept", "text/event-stream")
+	}
+	request.Headers = headers
+	stream := &eventStream{retry: defaultEventRetry}
+	for reconnects := 0; ; reconnects++ {
+		if stream.lastID != "" {
+			headers.Set("Last-Event-ID", stream.lastID)
+		}
+		resp, err := bf.FetchResponse(request)
+		if err != nil {
+			return nil, err
+		}
+		stream.r = bufio.NewReader(resp)
+		for err == nil {
+			var event SSEvent
+			if event, err = stream.next(); err == nil {
+				if fnErr := fn(event); fnErr != nil {
+					resp.Close()
+					return resp, fnErr
+				}
+			}
+		}
+		resp.Close()
+		if reconnects >= bf.EventReconnects {
+			if err == io.EOF {
+				err = nil
+			}
+			return resp, err
+		}
+		if request.Context == nil {
+			time.Sleep(stream.retry)
+			continue
+		}
+		timer := time.NewTimer(stream.retry)
+		select {
+		case <-timer.C:
+		case <-request.Context.Done():
+			timer.Stop()
+			return resp, request.Context.Err()
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_FetchEvents(t *testing.T) {
+	var connections int32
+	var lastEventIDs []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		if r.Header.Get("Accept") != "text/event-stream" {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		switch atomic.AddInt32(&connections, 1) {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "retry: 10\n: comment\n\n")
+			w.(http.Flusher).Flush()
+			fmt.Fprint(w, "id: 1\ndata: first\ndata:  line\n\n")
+			w.(http.Flusher).Flush()
+			fmt.Fprint(w, "event: update\r\ndata: {\"n\":2}\r\n\r\n")
+			//an event cut off by a dropped connection is lost
+			fmt.Fprint(w, "id: 3\ndata: lost")
+		default:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "id: 4\ndata\n\n")
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.EventReconnects = 1
+	var events []SSEvent
+	resp, err := fetcher.FetchEvents(Request{URL: ts.URL}, func(event SSEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "text/event-stream", resp.GetHeaders().Get("Content-Type"))
+	assert.Equal(t, []SSEvent{
+		{ID: "1", Event: "message", Data: "first\n line"},
+		{ID: "1", Event: "update", Data: `{"n":2}`},
+		{ID: "4", Event: "message", Data: ""},
+	}, events)
+	assert.Equal(t, []string{"", "1"}, lastEventIDs)
+
+	//no reconnection by default
+	atomic.StoreInt32(&connections, 0)
+	fetcher.EventReconnects = 0
+	_, err = fetcher.FetchEvents(Request{URL: ts.URL}, func(event SSEvent) error { return nil })
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+
+	stop := errors.New("stop")
+	atomic.StoreInt32(&connections, 0)
+	_, err = fetcher.FetchEvents(Request{URL: ts.URL}, func(event SSEvent) error { return stop })
+	assert.Equal(t, stop, err)
+}
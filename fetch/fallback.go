@@ -0,0 +1,90 @@
+package fetch
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// FallbackFetcher fetches requests with Primary, typically ChromeFetcher,
+// and retries them with Fallback, typically BaseFetcher, if Primary fails
+// because its own infrastructure is unavailable, e.g. Chrome is down. The
+// fallback loses JS rendering but keeps a crawl going. Errors of the target
+// site like errs.NotFound are returned as is. Both fetchers share one cookie
+// jar.
+type FallbackFetcher struct {
+	Primary  Fetcher
+	Fallback Fetcher
+	// ShouldFallback reports whether a request Primary failed with err is to
+	// be fetched with Fallback. If it is nil network errors fall back, see
+	// IsConnectionError.
+	ShouldFallback func(err error) bool
+}
+
+// NewFallbackFetcher returns FallbackFetcher falling back from primary to
+// fallback. The cookie jar of fallback, if any, is set to primary too.
+func NewFallbackFetcher(primary, fallback Fetcher) *FallbackFetcher {
+	if jar := fallback.getCookieJar(); jar != nil {
+		primary.setCookieJar(jar)
+	}
+	return &FallbackFetcher{Primary: primary, Fallback: fallback}
+}
+
+// IsConnectionError reports whether err is a failure to reach a host, like a
+// refused or reset connection, a failed DNS lookup or a timeout, rather than
+// an error response. Other errors of HTTP clients, e.g. invalid certificates
+// or too many redirects, are not.
+func IsConnectionError(err error) bool {
+	if e, ok := err.(*url.Error); ok {
+		err = e.Err
+	}
+	//newer net/http wraps errors of dialing a proxy
+	if e, ok := err.(*net.OpError); ok && e.Op == "proxyconnect" {
+		err = e.Err
+	}
+	switch e := err.(type) {
+	case *net.OpError:
+		return e.Op == "dial" || e.Op == "read" || e.Timeout()
+	case *net.DNSError:
+		return true
+	case net.Error:
+		return e.Timeout()
+	}
+	return false
+}
+
+// Fetch fetches request with Primary or, if it fails as defined by
+// ShouldFallback, with Fallback.
+func (f *FallbackFetcher) Fetch(request Request) (io.ReadCloser, error) {
+	content, err := f.Primary.Fetch(request)
+	if err == nil {
+		return content, nil
+	}
+	shouldFallback := f.ShouldFallback
+	if shouldFallback == nil {
+		shouldFallback = IsConnectionError
+	}
+	if !shouldFallback(err) {
+		return nil, err
+	}
+	logger.Warningf("Falling back to %T for %s: %s", f.Fallback, request.URL, err)
+	return f.Fallback.Fetch(request)
+}
+
+// CookiesForURL returns the cookies of the shared cookie jar to be sent to the URL.
+func (f *FallbackFetcher) CookiesForURL(u string) ([]*http.Cookie, error) {
+	return f.Fallback.CookiesForURL(u)
+}
+
+func (f *FallbackFetcher) getCookieJar() CookieJar {
+	return f.Fallback.getCookieJar()
+}
+
+func (f *FallbackFetcher) setCookieJar(jar CookieJar) {
+	f.Primary.setCookieJar(jar)
+	f.Fallback.setCookieJar(jar)
+}
+
+// Static type assertion
+var _ Fetcher = &FallbackFetcher{}
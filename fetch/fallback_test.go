@@ -0,0 +1,99 @@
+package fetch
+
+import (
+	"crypto/x509"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+//failingFetcher fails every request with err.
+type failingFetcher struct {
+	namedFetcher
+	err error
+}
+
+func (f *failingFetcher) Fetch(request Request) (io.ReadCloser, error) {
+	return nil, f.err
+}
+
+func TestFallbackFetcher(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	//Chrome is down
+	down, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	down.Close()
+	chrome := viper.GetString("CHROME")
+	defer viper.Set("CHROME", chrome)
+	viper.Set("CHROME", "http://"+down.Addr().String())
+
+	base, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	f := NewFallbackFetcher(newChromeFetcher(), base)
+	content, err := f.Fetch(Request{URL: ts.URL, Type: "chrome"})
+	if assert.NoError(t, err) {
+		data, _ := ioutil.ReadAll(content)
+		assert.Equal(t, helloContent, data)
+	}
+
+	//errors of the target site don't fall back
+	f = NewFallbackFetcher(base, &namedFetcher{name: "fallback"})
+	_, err = f.Fetch(Request{URL: ts.URL + "/missing"})
+	assert.IsType(t, &errs.NotFound{}, err)
+
+	custom := errors.New("render failed")
+	f = NewFallbackFetcher(&failingFetcher{err: custom}, &namedFetcher{name: "fallback"})
+	_, err = f.Fetch(Request{URL: ts.URL})
+	assert.Equal(t, custom, err)
+	f.ShouldFallback = func(err error) bool { return err == custom }
+	content, err = f.Fetch(Request{URL: ts.URL})
+	if assert.NoError(t, err) {
+		data, _ := ioutil.ReadAll(content)
+		assert.Equal(t, "fallback", string(data))
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	connection := []error{
+		dial,
+		&url.Error{Op: "Get", URL: "http://example.com", Err: dial},
+		&url.Error{Op: "Get", URL: "http://example.com", Err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}},
+		&url.Error{Op: "Get", URL: "http://example.com", Err: &net.OpError{Op: "proxyconnect", Net: "tcp", Err: dial}},
+		&url.Error{Op: "Get", URL: "http://example.com", Err: &net.DNSError{Err: "no such host", Name: "example.com"}},
+	}
+	for _, err := range connection {
+		assert.True(t, IsConnectionError(err), err.Error())
+	}
+	other := []error{
+		&url.Error{Op: "Get", URL: "https://example.com", Err: x509.UnknownAuthorityError{}},
+		&url.Error{Op: "Get", URL: "http://example.com", Err: errors.New("stopped after 10 redirects")},
+		&url.Error{Op: "Get", URL: "http://example.com", Err: errors.New("Proxy Authentication Required")},
+		&errs.NotFound{URL: "http://example.com"},
+	}
+	for _, err := range other {
+		assert.False(t, IsConnectionError(err), err.Error())
+	}
+
+	//Primary failing with a bad certificate doesn't fall back
+	f := NewFallbackFetcher(&failingFetcher{err: other[0]}, &namedFetcher{name: "fallback"})
+	_, err := f.Fetch(Request{URL: "https://example.com"})
+	assert.Equal(t, other[0], err)
+}
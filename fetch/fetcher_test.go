@@ -1,10 +1,18 @@
 package fetch
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/slotix/dataflowkit/errs"
 	"github.com/spf13/viper"
 
 	"github.com/stretchr/testify/assert"
@@ -148,3 +156,232 @@ func TestInvalidFetcher(t *testing.T) {
 	fetcher := newFetcher(fType)
 	assert.NotNil(t, fetcher)
 }
+
+func TestSupportedTypes(t *testing.T) {
+	assert.Equal(t, []Type{Base, Chrome}, SupportedTypes())
+	assert.True(t, IsSupported(Base))
+	assert.True(t, IsSupported("chrome"))
+	assert.False(t, IsSupported("unknownFetcher"))
+
+	//the returned slice is a copy
+	SupportedTypes()[0] = "unknownFetcher"
+	assert.True(t, IsSupported(Base))
+}
+
+func TestBaseFetcher_BodySnippet(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status/403":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("Access denied by firewall"))
+		case "/status/500":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "database is down"}`))
+		case "/status/404":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/status/403"})
+	if assert.IsType(t, &errs.Forbidden{}, err) {
+		assert.Equal(t, "Access denied by firewall", string(err.(*errs.Forbidden).BodySnippet()))
+	}
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/status/500"})
+	if assert.IsType(t, &errs.InternalServerError{}, err) {
+		assert.Equal(t, `{"error": "database is down"}`, string(err.(*errs.InternalServerError).BodySnippet()))
+	}
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/status/404"})
+	if assert.IsType(t, &errs.NotFound{}, err) {
+		assert.Nil(t, err.(*errs.NotFound).BodySnippet())
+	}
+
+	fetcher.BodySnippetSize = 6
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/status/403"})
+	assert.Equal(t, "Access", string(err.(*errs.Forbidden).BodySnippet()))
+	fetcher.BodySnippetSize = -1
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/status/403"})
+	assert.Nil(t, err.(*errs.Forbidden).BodySnippet())
+}
+
+func TestBaseFetcher_StatusErrorMap(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status/403":
+			w.WriteHeader(http.StatusForbidden)
+		case "/status/404":
+			w.WriteHeader(http.StatusNotFound)
+		case "/status/429":
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.StatusErrorMap = map[int]func(url string) error{
+		//rate limiting firewall, worth retrying
+		403: func(url string) error { return &errs.InternalServerError{} },
+		429: func(url string) error { return &errs.NotFound{URL: url} },
+		404: func(url string) error { return nil },
+	}
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/status/403"})
+	assert.IsType(t, &errs.InternalServerError{}, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/status/429"})
+	if assert.IsType(t, &errs.NotFound{}, err) {
+		assert.Equal(t, ts.URL+"/status/429", err.(*errs.NotFound).URL)
+	}
+	//nil falls back to the built-in error
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/status/404"})
+	assert.IsType(t, &errs.NotFound{}, err)
+}
+
+func TestBaseFetcher_Context(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	fetcher, err := NewBaseFetcherWithOptions(WithRetryPolicy(&RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = fetcher.Fetch(Request{URL: ts.URL, Context: ctx})
+	assert.IsType(t, &errs.Canceled{}, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&hits), "canceled requests are not retried")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = fetcher.Fetch(Request{URL: ts.URL, Context: ctx})
+	assert.IsType(t, &errs.GatewayTimeout{}, err)
+}
+
+func TestBaseFetcher_HostOverride(t *testing.T) {
+	var hosts []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		switch r.URL.Path {
+		case "/relative":
+			http.Redirect(w, r, "/target", http.StatusFound)
+		case "/absolute":
+			http.Redirect(w, r, "http://"+r.Context().Value(http.LocalAddrContextKey).(net.Addr).String()+"/target", http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	for _, path := range []string{"/", "/relative", "/absolute"} {
+		content, err := fetcher.Fetch(Request{URL: ts.URL + path, HostOverride: "www.example.com"})
+		if assert.NoError(t, err) {
+			content.Close()
+		}
+	}
+	//relative redirects keep the host, absolute ones are sent with their own
+	assert.Equal(t, []string{"www.example.com", "www.example.com", "www.example.com", "www.example.com", ts.Listener.Addr().String()}, hosts)
+}
+
+func TestBaseFetcher_URLRewriter(t *testing.T) {
+	var queries []string
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Write([]byte("mirror"))
+	}))
+	defer mirror.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.URLRewriter = func(rawurl string) (string, error) {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return "", err
+		}
+		if u.Host != "public.example.com" {
+			return rawurl, nil
+		}
+		u.Scheme = "http"
+		u.Host = mirror.Listener.Addr().String()
+		q := u.Query()
+		q.Set("nocache", "1")
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	resp, err := fetcher.FetchResponse(Request{URL: "https://public.example.com/page?id=7"})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(resp)
+	assert.NoError(t, err)
+	resp.Close()
+	assert.Equal(t, "mirror", string(data))
+	assert.Equal(t, mirror.URL+"/page?id=7&nocache=1", resp.GetURL())
+	assert.Equal(t, []string{"id=7&nocache=1"}, queries)
+
+	fetcher.URLRewriter = func(rawurl string) (string, error) {
+		return "", errors.New("no mirror")
+	}
+	_, err = fetcher.Fetch(Request{URL: "https://public.example.com/page"})
+	assert.IsType(t, &errs.BadRequest{}, err)
+}
+
+func TestRequest_ProxyURL(t *testing.T) {
+	//proxy answers plain HTTP requests itself, naming the proxy and the requested URL
+	proxy := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + " " + r.URL.String()))
+		}))
+	}
+	regional := proxy("regional")
+	defer regional.Close()
+	global := proxy("global")
+	defer global.Close()
+	fetch := func(fetcher *BaseFetcher, r Request) string {
+		content, err := fetcher.Fetch(r)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		defer content.Close()
+		data, err := ioutil.ReadAll(content)
+		assert.NoError(t, err)
+		return string(data)
+	}
+
+	fetcher, err := NewBaseFetcherWithOptions(WithProxy(global.URL))
+	assert.NoError(t, err)
+	assert.Equal(t, "global http://example.com/", fetch(fetcher, Request{URL: "http://example.com/"}))
+	assert.Equal(t, "regional http://example.de/", fetch(fetcher, Request{URL: "http://example.de/", ProxyURL: regional.URL}))
+	assert.Equal(t, "global http://example.com/a", fetch(fetcher, Request{URL: "http://example.com/a"}))
+
+	//fetchers without proxy route single requests too
+	fetcher, err = NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, "regional http://example.de/", fetch(fetcher, Request{URL: "http://example.de/", ProxyURL: regional.URL}))
+
+	_, err = fetcher.Fetch(Request{URL: "http://example.de/", ProxyURL: "http://[::1]:namedport"})
+	assert.IsType(t, &errs.BadRequest{}, err)
+
+	//request proxies are subject to host restrictions, by address and, once
+	//resolved, by name
+	var hits int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer internal.Close()
+	_, port, _ := net.SplitHostPort(internal.Listener.Addr().String())
+	fetcher, err = NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.BlockPrivateNetworks = true
+	for _, proxyURL := range []string{internal.URL, "http://localhost:" + port} {
+		_, err = fetcher.Fetch(Request{URL: "http://example.com/admin", ProxyURL: proxyURL})
+		assert.IsType(t, &errs.ForbiddenHost{}, err, proxyURL)
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&hits))
+}
@@ -0,0 +1,100 @@
+package fetch
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// SubmitForm fetches the page of pageRequest, finds the first form matching
+// formSelector and submits it to its action with its method, e.g. to log in.
+// The fields of the form, including hidden ones like CSRF tokens, are sent
+// with their values in the page, values replaces or adds fields. The
+// submission is made like pageRequest, so a cookie jar of BaseFetcher keeps
+// the session of the page. A page without matching form or a form with
+// multipart/form-data encoding returns errs.BadDocument. The page is
+// returned by FetchDocument, see it for its errors.
+func (bf *BaseFetcher) SubmitForm(pageRequest Request, formSelector string, values map[string]string) (*Response, error) {
+	doc, page, err := bf.FetchDocument(pageRequest)
+	if err != nil {
+		return nil, err
+	}
+	form := doc.Find(formSelector).Filter("form").First()
+	if form.Length() == 0 {
+		return nil, &errs.BadDocument{URL: page.GetURL(), Err: fmt.Errorf("no form matches %q", formSelector)}
+	}
+	if strings.EqualFold(form.AttrOr("enctype", ""), "multipart/form-data") {
+		return nil, &errs.BadDocument{URL: page.GetURL(), Err: fmt.Errorf("form %q has unsupported multipart/form-data encoding", formSelector)}
+	}
+	base, err := url.Parse(page.GetURL())
+	if err != nil {
+		return nil, &errs.BadRequest{Err: err}
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if baseHref, err := base.Parse(href); err == nil {
+			base = baseHref
+		}
+	}
+	action, err := base.Parse(strings.TrimSpace(form.AttrOr("action", "")))
+	if err != nil {
+		return nil, &errs.BadDocument{URL: page.GetURL(), Err: err}
+	}
+	action.Fragment = ""
+	fields := formFields(form)
+	for name, value := range values {
+		fields.Set(name, value)
+	}
+	submit := pageRequest
+	submit.URL = action.String()
+	submit.FormData = ""
+	submit.form = nil
+	if strings.EqualFold(form.AttrOr("method", ""), "post") {
+		submit.Method = "POST"
+		submit.FormData = fields.Encode()
+		submit.form = fields
+	} else {
+		//GET forms replace the query of their action
+		submit.Method = "GET"
+		action.RawQuery = fields.Encode()
+		submit.URL = action.String()
+	}
+	return bf.FetchResponse(submit)
+}
+
+// formFields returns the fields of form a browser would submit, without
+// submit buttons and file inputs.
+func formFields(form *goquery.Selection) url.Values {
+	fields := url.Values{}
+	form.Find("input, select, textarea").Each(func(_ int, s *goquery.Selection) {
+		name := s.AttrOr("name", "")
+		if _, disabled := s.Attr("disabled"); name == "" || disabled {
+			return
+		}
+		switch goquery.NodeName(s) {
+		case "input":
+			switch strings.ToLower(s.AttrOr("type", "text")) {
+			case "submit", "button", "image", "reset", "file":
+			case "checkbox", "radio":
+				if _, checked := s.Attr("checked"); checked {
+					fields.Add(name, s.AttrOr("value", "on"))
+				}
+			default:
+				fields.Add(name, s.AttrOr("value", ""))
+			}
+		case "select":
+			option := s.Find("option[selected]").First()
+			if option.Length() == 0 {
+				option = s.Find("option").First()
+			}
+			if option.Length() > 0 {
+				fields.Add(name, option.AttrOr("value", strings.TrimSpace(option.Text())))
+			}
+		case "textarea":
+			fields.Add(name, s.Text())
+		}
+	})
+	return fields
+}
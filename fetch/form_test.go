@@ -0,0 +1,83 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+const loginPage = `<html><body>
+<form id="search" action="/search"><input name="q"></form>
+<form id="login" action="/session?from=page#top" method="post">
+	<input type="hidden" name="csrf" value="tok+en/==&amp;1">
+	<input name="user" value="guest">
+	<input type="password" name="password">
+	<input type="checkbox" name="remember" checked>
+	<input type="checkbox" name="newsletter" value="yes">
+	<input name="disabled" value="x" disabled>
+	<select name="lang"><option value="en">English</option><option value="de" selected>Deutsch</option></select>
+	<textarea name="note">hi</textarea>
+	<input type="submit" name="go" value="Log in">
+</form>
+</body></html>`
+
+func TestBaseFetcher_SubmitForm(t *testing.T) {
+	var submitted *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "1"})
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(loginPage))
+		default:
+			r.ParseForm()
+			submitted = r
+			w.Write([]byte("welcome"))
+		}
+	}))
+	defer ts.Close()
+
+	jar, _ := cookiejar.New(nil)
+	fetcher, err := NewBaseFetcherWithOptions(WithCookieJar(NewCookieJar(jar)))
+	assert.NoError(t, err)
+	resp, err := fetcher.SubmitForm(Request{URL: ts.URL + "/login"}, "#login", map[string]string{
+		"user":     "alice",
+		"password": "secret",
+	})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(resp)
+	assert.NoError(t, err)
+	resp.Close()
+	assert.Equal(t, "welcome", string(data))
+	if assert.NotNil(t, submitted) {
+		assert.Equal(t, "POST", submitted.Method)
+		assert.Equal(t, "/session", submitted.URL.Path)
+		assert.Equal(t, "page", submitted.URL.Query().Get("from"))
+		assert.Equal(t, map[string][]string{
+			"csrf":     {"tok+en/==&1"},
+			"user":     {"alice"},
+			"password": {"secret"},
+			"remember": {"on"},
+			"lang":     {"de"},
+			"note":     {"hi"},
+		}, map[string][]string(submitted.PostForm))
+		sid, err := submitted.Cookie("sid")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "1", sid.Value)
+		}
+	}
+
+	//GET forms send their fields in the query
+	_, err = fetcher.SubmitForm(Request{URL: ts.URL + "/login"}, "#search", map[string]string{"q": "go"})
+	assert.NoError(t, err)
+	assert.Equal(t, "GET", submitted.Method)
+	assert.Equal(t, "/search?q=go", submitted.URL.RequestURI())
+
+	_, err = fetcher.SubmitForm(Request{URL: ts.URL + "/login"}, "#missing", nil)
+	assert.IsType(t, &errs.BadDocument{}, err)
+}
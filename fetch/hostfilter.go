@@ -0,0 +1,193 @@
+package fetch
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// privateNetworks are the address ranges rejected by BlockPrivateNetworks:
+// RFC 1918 private networks, carrier-grade NAT, loopback, unspecified
+// addresses connecting to the local host, link-local and unique local
+// addresses. IPv4-mapped IPv6 addresses match the IPv4 ranges.
+var privateNetworks = []string{
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"0.0.0.0/8",
+	"169.254.0.0/16",
+	"::1/128",
+	"::/128",
+	"fe80::/10",
+	"fc00::/7",
+}
+
+// normalizeHost returns host in lower case and without the trailing dot of
+// fully qualified names, which name the same host.
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// matchHost reports whether host matches pattern. A pattern starting with
+// "*." matches any subdomain of the rest of the pattern.
+func matchHost(pattern, host string) bool {
+	pattern = normalizeHost(pattern)
+	host = normalizeHost(host)
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return pattern == host
+}
+
+// parseIPNet parses an IP address or CIDR range entry of host lists.
+func parseIPNet(entry string) (*net.IPNet, bool) {
+	if strings.Contains(entry, "/") {
+		_, ipNet, err := net.ParseCIDR(entry)
+		return ipNet, err == nil
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, false
+	}
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 8 * net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, true
+}
+
+// hostListMatch reports whether host or ip matches any entry of list.
+// Name entries are matched against host, IP and CIDR entries against ip.
+func hostListMatch(list []string, host string, ip net.IP) bool {
+	for _, entry := range list {
+		if ipNet, ok := parseIPNet(entry); ok {
+			if ip != nil && ipNet.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if matchHost(entry, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasIPEntries reports whether list contains IP or CIDR entries.
+func hasIPEntries(list []string) bool {
+	for _, entry := range list {
+		if _, ok := parseIPNet(entry); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filtersHosts reports whether BaseFetcher restricts hosts it may connect to.
+func (bf *BaseFetcher) filtersHosts() bool {
+	return len(bf.AllowedHosts) > 0 || len(bf.BlockedHosts) > 0 || bf.BlockPrivateNetworks
+}
+
+// checkHost checks the host of a request URL against AllowedHosts and
+// BlockedHosts. Hosts that are not IP addresses can only be checked against
+// host name entries here, IP ranges are checked by checkIP once the host is resolved.
+func (bf *BaseFetcher) checkHost(host string) error {
+	if !bf.filtersHosts() {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return bf.checkIP(host, ip)
+	}
+	if hostListMatch(bf.BlockedHosts, host, nil) {
+		return &errs.ForbiddenHost{Host: host}
+	}
+	if len(bf.AllowedHosts) > 0 &&
+		!hostListMatch(bf.AllowedHosts, host, nil) &&
+		!hasIPEntries(bf.AllowedHosts) {
+		return &errs.ForbiddenHost{Host: host}
+	}
+	return nil
+}
+
+// checkIP checks host resolved to ip against BlockPrivateNetworks, AllowedHosts and BlockedHosts.
+func (bf *BaseFetcher) checkIP(host string, ip net.IP) error {
+	if bf.BlockPrivateNetworks && hostListMatch(privateNetworks, "", ip) {
+		return &errs.ForbiddenHost{Host: host}
+	}
+	if hostListMatch(bf.BlockedHosts, host, ip) {
+		return &errs.ForbiddenHost{Host: host}
+	}
+	if len(bf.AllowedHosts) > 0 && !hostListMatch(bf.AllowedHosts, host, ip) {
+		return &errs.ForbiddenHost{Host: host}
+	}
+	return nil
+}
+
+// setKeepAlive makes req close its connection, i.e. send "Connection: close",
+// if its host is listed in NoKeepAliveHosts.
+func (bf *BaseFetcher) setKeepAlive(req *http.Request) {
+	if len(bf.NoKeepAliveHosts) == 0 {
+		return
+	}
+	host := req.URL.Hostname()
+	req.Close = hostListMatch(bf.NoKeepAliveHosts, host, net.ParseIP(host))
+}
+
+// hostHeaders returns the PerHostHeaders entries matching host, entries of
+// more specific, i.e. longer, patterns first.
+func (bf *BaseFetcher) hostHeaders(host string) []http.Header {
+	var patterns []string
+	for pattern := range bf.PerHostHeaders {
+		if matchHost(pattern, host) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	headers := make([]http.Header, len(patterns))
+	for i, pattern := range patterns {
+		headers[i] = bf.PerHostHeaders[pattern]
+	}
+	return headers
+}
+
+// setHostHeaders sets the PerHostHeaders of host in h which are not set yet.
+func (bf *BaseFetcher) setHostHeaders(h http.Header, host string) {
+	for _, headers := range bf.hostHeaders(host) {
+		for name, values := range headers {
+			name = http.CanonicalHeaderKey(name)
+			if _, ok := h[name]; !ok {
+				h[name] = values
+			}
+		}
+	}
+}
+
+// switchHostHeaders replaces the PerHostHeaders of the host redirected from
+// with the ones of the host of req, so headers like API keys are not sent to
+// other hosts.
+func (bf *BaseFetcher) switchHostHeaders(req, from *http.Request) {
+	if len(bf.PerHostHeaders) == 0 || strings.EqualFold(req.URL.Hostname(), from.URL.Hostname()) {
+		return
+	}
+	for _, headers := range bf.hostHeaders(from.URL.Hostname()) {
+		for name, values := range headers {
+			name = http.CanonicalHeaderKey(name)
+			if strings.Join(req.Header[name], "\n") == strings.Join(values, "\n") {
+				req.Header.Del(name)
+			}
+		}
+	}
+	bf.setHostHeaders(req.Header, req.URL.Hostname())
+	bf.setDefaultHeaders(req.Header)
+}
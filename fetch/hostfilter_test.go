@@ -0,0 +1,214 @@
+package fetch
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchHost(t *testing.T) {
+	assert.True(t, matchHost("example.com", "Example.com"))
+	assert.False(t, matchHost("example.com", "www.example.com"))
+	assert.True(t, matchHost("*.example.com", "www.example.com"))
+	assert.False(t, matchHost("*.example.com", "example.com"))
+	assert.False(t, matchHost("*.example.com", "badexample.com"))
+	//fully qualified names
+	assert.True(t, matchHost("example.com", "EXAMPLE.com."))
+	assert.True(t, matchHost("*.example.com", "www.example.com."))
+	assert.True(t, matchHost("example.com.", "example.com"))
+}
+
+func TestBaseFetcher_BlockedHosts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+			return
+		}
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.BlockedHosts = []string{"169.254.0.0/16", "*.internal"}
+
+	//metadata IP literal
+	_, err = fetcher.Fetch(Request{URL: "http://169.254.169.254/latest/meta-data/"})
+	assert.IsType(t, &errs.ForbiddenHost{}, err)
+	//blocked host name
+	_, err = fetcher.Fetch(Request{URL: "http://db.internal/"})
+	assert.IsType(t, &errs.ForbiddenHost{}, err)
+	fetcher.BlockedHosts = append(fetcher.BlockedHosts, "blocked.example")
+	_, err = fetcher.Fetch(Request{URL: "http://Blocked.Example./"})
+	assert.IsType(t, &errs.ForbiddenHost{}, err)
+	//allowed host redirecting to metadata IP
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/redirect"})
+	assert.IsType(t, &errs.ForbiddenHost{}, err)
+	//host name resolving to metadata IP
+	fetcher.ResolveOverride = map[string]string{"metadata.example.com": "169.254.169.254"}
+	_, err = fetcher.Fetch(Request{URL: "http://metadata.example.com/latest/meta-data/"})
+	assert.IsType(t, &errs.ForbiddenHost{}, err)
+}
+
+func TestBaseFetcher_AllowedHosts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.AllowedHosts = []string{"127.0.0.0/8"}
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+
+	fetcher.AllowedHosts = []string{"example.com"}
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.IsType(t, &errs.ForbiddenHost{}, err)
+	_, err = fetcher.Fetch(Request{URL: "http://example.org/"})
+	assert.IsType(t, &errs.ForbiddenHost{}, err)
+	//allowed name
+	fetcher.ResolveOverride = map[string]string{"example.com": "127.0.0.1:" + port}
+	_, err = fetcher.Fetch(Request{URL: "http://example.com/"})
+	assert.NoError(t, err)
+}
+
+func TestBaseFetcher_BlockPrivateNetworks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+
+	fetcher.BlockPrivateNetworks = true
+	for _, u := range []string{
+		"http://10.1.2.3/",
+		"http://172.16.5.4/",
+		"http://172.31.255.255/",
+		"http://192.168.1.1/",
+		"http://100.64.0.1/",
+		"http://100.127.255.255/",
+		"http://127.0.0.1/",
+		"http://0.0.0.0/",
+		"http://169.254.169.254/",
+		"http://[::1]/",
+		"http://[::]/",
+		"http://[::ffff:127.0.0.1]/",
+		"http://[::ffff:10.0.0.1]/",
+		"http://[fe80::1]/",
+		"http://[fd00::1]/",
+		ts.URL,
+	} {
+		_, err = fetcher.Fetch(Request{URL: u})
+		assert.IsType(t, &errs.ForbiddenHost{}, err, u)
+	}
+	assert.NoError(t, fetcher.checkIP("example.com", net.ParseIP("93.184.216.34")))
+	assert.NoError(t, fetcher.checkIP("example.com", net.ParseIP("172.32.0.1")))
+	assert.NoError(t, fetcher.checkIP("example.com", net.ParseIP("100.128.0.1")))
+	assert.NoError(t, fetcher.checkIP("example.com", net.ParseIP("::ffff:93.184.216.34")))
+
+	//unspecified address connecting to the local host
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	_, err = fetcher.Fetch(Request{URL: "http://0.0.0.0:" + port + "/"})
+	assert.IsType(t, &errs.ForbiddenHost{}, err)
+
+	//public looking host name rebound to loopback
+	fetcher.ResolveOverride = map[string]string{"rebind.example.com": ts.Listener.Addr().String()}
+	_, err = fetcher.Fetch(Request{URL: "http://rebind.example.com/"})
+	assert.IsType(t, &errs.ForbiddenHost{}, err)
+}
+
+func TestBaseFetcher_NoKeepAliveHosts(t *testing.T) {
+	closed := map[string]bool{}
+	var legacyURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		closed[r.Host+r.URL.Path] = r.Close && r.Header.Get("Connection") == "close"
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, legacyURL+"/target", http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	legacyURL = "http://legacy.example.com:" + port
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.ResolveOverride = map[string]string{"legacy.example.com": "127.0.0.1"}
+	fetcher.NoKeepAliveHosts = []string{"*.example.com"}
+	for _, u := range []string{legacyURL + "/", ts.URL + "/", ts.URL + "/redirect"} {
+		content, err := fetcher.Fetch(Request{URL: u})
+		if assert.NoError(t, err) {
+			content.Close()
+		}
+	}
+	assert.Equal(t, map[string]bool{
+		"legacy.example.com:" + port + "/":       true,
+		"127.0.0.1:" + port + "/":                false,
+		"127.0.0.1:" + port + "/redirect":        false,
+		"legacy.example.com:" + port + "/target": true,
+	}, closed)
+}
+
+func TestBaseFetcher_PerHostHeaders(t *testing.T) {
+	headers := map[string]http.Header{}
+	var port string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers[r.Host+r.URL.Path] = r.Header
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://shop.example.com:"+port+"/target", http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+	_, port, _ = net.SplitHostPort(ts.Listener.Addr().String())
+
+	fetcher, err := NewBaseFetcherWithOptions(WithUserAgent("dfk"))
+	assert.NoError(t, err)
+	fetcher.ResolveOverride = map[string]string{
+		"api.example.com":  "127.0.0.1",
+		"shop.example.com": "127.0.0.1",
+	}
+	fetcher.PerHostHeaders = map[string]http.Header{
+		"api.example.com": {"X-Api-Key": {"api"}, "User-Agent": {"api"}},
+		"*.example.com":   {"X-Api-Key": {"any"}, "Referer": {"https://example.com/"}},
+	}
+	for _, r := range []Request{
+		{URL: "http://api.example.com:" + port + "/"},
+		{URL: "http://shop.example.com:" + port + "/", Headers: http.Header{"Referer": {"https://google.com/"}}},
+		{URL: ts.URL + "/"},
+	} {
+		content, err := fetcher.Fetch(r)
+		if assert.NoError(t, err) {
+			content.Close()
+		}
+	}
+	api := headers["api.example.com:"+port+"/"]
+	assert.Equal(t, "api", api.Get("X-Api-Key"))
+	assert.Equal(t, "api", api.Get("User-Agent"))
+	shop := headers["shop.example.com:"+port+"/"]
+	assert.Equal(t, "any", shop.Get("X-Api-Key"))
+	assert.Equal(t, "https://google.com/", shop.Get("Referer"))
+	assert.Equal(t, "dfk", shop.Get("User-Agent"))
+	local := headers["127.0.0.1:"+port+"/"]
+	assert.Equal(t, "", local.Get("X-Api-Key"))
+	assert.Equal(t, "dfk", local.Get("User-Agent"))
+
+	//a redirect to another host sends the headers of that host
+	content, err := fetcher.Fetch(Request{URL: "http://api.example.com:" + port + "/redirect"})
+	if assert.NoError(t, err) {
+		content.Close()
+	}
+	target := headers["shop.example.com:"+port+"/target"]
+	assert.Equal(t, "any", target.Get("X-Api-Key"))
+	assert.Equal(t, "dfk", target.Get("User-Agent"))
+}
@@ -0,0 +1,66 @@
+package fetch
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// hostLimiter limits the number of simultaneous requests to a host.
+// The zero value is ready to use.
+type hostLimiter struct {
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// acquireContext blocks until one of max slots of host is free and takes it
+// or gives up waiting with the error of ctx once it is done. The number of
+// slots of a host is fixed by the first call.
+func (l *hostLimiter) acquireContext(ctx context.Context, host string, max int) error {
+	l.mu.Lock()
+	if l.slots == nil {
+		l.slots = make(map[string]chan struct{})
+	}
+	slots, ok := l.slots[host]
+	if !ok {
+		slots = make(chan struct{}, max)
+		l.slots[host] = slots
+	}
+	l.mu.Unlock()
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot of host taken by acquireContext.
+func (l *hostLimiter) release(host string) {
+	l.mu.Lock()
+	slots := l.slots[host]
+	l.mu.Unlock()
+	<-slots
+}
+
+// releasingBody calls release once the body is read till the end or closed,
+// which ends the request from the host point of view.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
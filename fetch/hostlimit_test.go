@@ -0,0 +1,143 @@
+package fetch
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_MaxConcurrentPerHost(t *testing.T) {
+	var current, max int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.MaxConcurrentPerHost = 2
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			content, err := fetcher.Fetch(Request{URL: ts.URL})
+			if assert.NoError(t, err) {
+				ioutil.ReadAll(content)
+				content.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&max))
+
+	//slots are freed after errors
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/%%"})
+	assert.Error(t, err)
+	fetcher.MaxConcurrentPerHost = 1
+	for i := 0; i < 3; i++ {
+		_, err = fetcher.Fetch(Request{URL: "http://127.0.0.1:1/"})
+		assert.Error(t, err)
+	}
+}
+
+func TestBaseFetcher_MaxConcurrentPerHostContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.MaxConcurrentPerHost = 1
+	//the slot is taken until the body is closed
+	content, err := fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	_, err = fetcher.Fetch(Request{URL: ts.URL, Context: ctx})
+	assert.IsType(t, &errs.Canceled{}, err)
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = fetcher.Fetch(Request{URL: ts.URL, Context: ctx})
+	assert.IsType(t, &errs.GatewayTimeout{}, err)
+
+	content.Close()
+	content, err = fetcher.Fetch(Request{URL: ts.URL})
+	if assert.NoError(t, err) {
+		content.Close()
+	}
+}
+
+func TestChromeFetcher_MaxConcurrentRenders(t *testing.T) {
+	var current, max int32
+	//Chrome failing to open pages after a while, renders end with an error
+	chrome := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer chrome.Close()
+	address := viper.GetString("CHROME")
+	viper.Set("CHROME", chrome.URL)
+	defer viper.Set("CHROME", address)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			//the limit is shared by fetchers rendering with the same Chrome
+			f := newChromeFetcher()
+			f.MaxConcurrentRenders = 3
+			_, err := f.Fetch(Request{URL: "http://example.com"})
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), atomic.LoadInt32(&max))
+
+	//waiting for a render slot ends with the request
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, chromeRenders.acquireContext(context.Background(), chrome.URL, 3))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	f := newChromeFetcher()
+	f.MaxConcurrentRenders = 3
+	_, err := f.Fetch(Request{URL: "http://example.com", Context: ctx})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	for i := 0; i < 3; i++ {
+		chromeRenders.release(chrome.URL)
+	}
+}
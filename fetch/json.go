@@ -0,0 +1,64 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// FetchJSON fetches a JSON document and unmarshals it into v. Accept is
+// sent as application/json unless set in request.Headers. A response with
+// a Content-Type other than JSON, unless request.AnyContentType is set, or a
+// body which can't be unmarshaled into v returns errs.InvalidJSON with the
+// beginning of the body. The body of the returned Response is consumed,
+// its metadata is still available.
+func (bf *BaseFetcher) FetchJSON(request Request, v interface{}) (*Response, error) {
+	if request.Headers.Get("Accept") == "" {
+		headers := http.Header{}
+		for name, values := range request.Headers {
+			headers[name] = values
+		}
+		headers.Set("Accept", "application/json")
+		request.Headers = headers
+	}
+	resp, err := bf.FetchResponse(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	data, err := ioutil.ReadAll(resp)
+	if err != nil {
+		return resp, err
+	}
+	if contentType := resp.GetHeaders().Get("Content-Type"); !request.AnyContentType && !isJSON(contentType) {
+		return resp, &errs.InvalidJSON{
+			URL:     resp.GetURL(),
+			Err:     fmt.Errorf("unexpected content type %q", contentType),
+			Snippet: errs.Snippet{Body: bf.readSnippet(bytes.NewReader(data))},
+		}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return resp, &errs.InvalidJSON{
+			URL:     resp.GetURL(),
+			Err:     err,
+			Snippet: errs.Snippet{Body: bf.readSnippet(bytes.NewReader(data))},
+		}
+	}
+	return resp, nil
+}
+
+// isJSON reports whether contentType is a JSON media type like
+// application/json, text/json or application/ld+json.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
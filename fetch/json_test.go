@@ -0,0 +1,55 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_FetchJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+		case "/invalid":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"name": "Laptop",`))
+			return
+		default:
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		}
+		w.Write([]byte(`{"name": "Laptop", "price": 999.5, "accept": "` + r.Header.Get("Accept") + `"}`))
+	}))
+	defer ts.Close()
+
+	type product struct {
+		Name   string
+		Price  float64
+		Accept string
+	}
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	var p product
+	resp, err := fetcher.FetchJSON(Request{URL: ts.URL}, &p)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.GetStatusCode())
+	assert.Equal(t, product{Name: "Laptop", Price: 999.5, Accept: "application/json"}, p)
+
+	_, err = fetcher.FetchJSON(Request{URL: ts.URL + "/text"}, &p)
+	if assert.IsType(t, &errs.InvalidJSON{}, err) {
+		assert.Contains(t, string(err.(*errs.InvalidJSON).BodySnippet()), "Laptop")
+	}
+	//the content type is not checked on request
+	p = product{}
+	_, err = fetcher.FetchJSON(Request{URL: ts.URL + "/text", AnyContentType: true}, &p)
+	assert.NoError(t, err)
+	assert.Equal(t, "Laptop", p.Name)
+
+	_, err = fetcher.FetchJSON(Request{URL: ts.URL + "/invalid"}, &p)
+	if assert.IsType(t, &errs.InvalidJSON{}, err) {
+		assert.Equal(t, `{"name": "Laptop",`, string(err.(*errs.InvalidJSON).BodySnippet()))
+	}
+}
@@ -0,0 +1,37 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// FetchLines fetches request and calls fn with each line of the response
+// body as it arrives, without the line terminator, e.g. for newline
+// delimited JSON feeds. The line passed to fn is only valid until fn returns.
+// Reading stops at the first error returned by fn, which is returned along
+// with the Response. The returned Response is read and closed, it keeps the
+// response metadata.
+func (bf *BaseFetcher) FetchLines(request Request, fn func(line []byte) error) (*Response, error) {
+	resp, err := bf.FetchResponse(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	r := bufio.NewReader(resp)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+			if fnErr := fn(line); fnErr != nil {
+				return resp, fnErr
+			}
+		}
+		if err == io.EOF {
+			return resp, nil
+		}
+		if err != nil {
+			return resp, err
+		}
+	}
+}
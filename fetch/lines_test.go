@@ -0,0 +1,51 @@
+package fetch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_FetchLines(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(w, "{\"n\":%d}\r\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+		fmt.Fprint(w, `{"n":4}`)
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+
+	var received []int
+	resp, err := fetcher.FetchLines(Request{URL: ts.URL}, func(line []byte) error {
+		var v struct{ N int }
+		if err := json.Unmarshal(line, &v); err != nil {
+			return err
+		}
+		received = append(received, v.N)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4}, received)
+	assert.Equal(t, "application/x-ndjson", resp.GetHeaders().Get("Content-Type"))
+
+	stop := errors.New("stop")
+	lines := 0
+	_, err = fetcher.FetchLines(Request{URL: ts.URL}, func(line []byte) error {
+		lines++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, lines)
+}
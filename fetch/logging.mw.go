@@ -26,12 +26,15 @@ func (mw loggingMiddleware) Fetch(req Request) (out io.ReadCloser, err error) {
 	defer func(begin time.Time) {
 		url := req.getURL()
 		if err == nil {
-			mw.logger.WithFields(
-				logrus.Fields{
-					"fetcher": req.Type,
-					"func":    "Fetch",
-					"took":    time.Since(begin),
-				}).Info("Fetch URL: ", url)
+			fields := logrus.Fields{
+				"fetcher": req.Type,
+				"func":    "Fetch",
+				"took":    time.Since(begin),
+			}
+			if req.CorrelationID != "" {
+				fields["correlationID"] = req.CorrelationID
+			}
+			mw.logger.WithFields(fields).Info("Fetch URL: ", url)
 		}
 		//don't log errors here. They all will be reported at transport.go func encodeError()
 	}(time.Now())
@@ -0,0 +1,55 @@
+package fetch
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// FetchMeta is a JSON serializable summary of a fetched document without
+// its content. It is meant for lightweight checks like link validation.
+type FetchMeta struct {
+	// URL is the final URL of the document after redirects.
+	URL         string      `json:"url"`
+	StatusCode  int         `json:"statusCode"`
+	Header      http.Header `json:"header"`
+	ContentType string      `json:"contentType"`
+	// Size is the number of bytes of the document body.
+	Size int64 `json:"size"`
+	// Duration is the time the fetch took including reading the body, in nanoseconds.
+	Duration time.Duration `json:"duration"`
+	// Cached is set if the document was served by SessionCache, CacheAge is
+	// then the time since it was fetched, in nanoseconds. Stale is set for
+	// expired copies served by OfflineFallback.
+	Cached   bool          `json:"cached"`
+	CacheAge time.Duration `json:"cacheAge"`
+	Stale    bool          `json:"stale"`
+}
+
+// FetchMeta fetches the document and returns its metadata. The body is read
+// to measure its size and discarded.
+func (bf *BaseFetcher) FetchMeta(request Request) (*FetchMeta, error) {
+	start := time.Now()
+	resp, err := bf.FetchResponse(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	size, err := io.Copy(ioutil.Discard, resp)
+	if err != nil {
+		return nil, err
+	}
+	cached, age := resp.FromCache()
+	return &FetchMeta{
+		URL:         resp.GetURL(),
+		StatusCode:  resp.GetStatusCode(),
+		Header:      resp.GetHeaders(),
+		ContentType: resp.GetHeaders().Get("Content-Type"),
+		Size:        size,
+		Duration:    time.Since(start),
+		Cached:      cached,
+		CacheAge:    age,
+		Stale:       resp.Stale(),
+	}, nil
+}
@@ -0,0 +1,60 @@
+package fetch
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_FetchMeta(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/hello", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	meta, err := fetcher.FetchMeta(Request{URL: ts.URL + "/redirect"})
+	assert.NoError(t, err)
+	assert.Equal(t, ts.URL+"/hello", meta.URL)
+	assert.Equal(t, http.StatusOK, meta.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", meta.ContentType)
+	assert.Equal(t, int64(len(helloContent)), meta.Size)
+	assert.True(t, meta.Duration > 0)
+	assert.False(t, meta.Cached)
+
+	data, err := json.Marshal(meta)
+	assert.NoError(t, err)
+	var fields map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &fields))
+	for _, key := range []string{"url", "statusCode", "header", "contentType", "size", "duration", "cached", "cacheAge", "stale"} {
+		assert.Contains(t, fields, key)
+	}
+
+	_, err = fetcher.FetchMeta(Request{URL: ts.URL + "/%%"})
+	assert.Error(t, err)
+
+	//documents served by SessionCache report their age
+	now := time.Now()
+	fetcher.SessionCache = NewSessionCache(0, time.Minute)
+	fetcher.SessionCache.now = func() time.Time { return now }
+	meta, err = fetcher.FetchMeta(Request{URL: ts.URL + "/hello"})
+	assert.NoError(t, err)
+	assert.False(t, meta.Cached)
+	now = now.Add(10 * time.Second)
+	meta, err = fetcher.FetchMeta(Request{URL: ts.URL + "/hello"})
+	assert.NoError(t, err)
+	assert.True(t, meta.Cached)
+	assert.Equal(t, 10*time.Second, meta.CacheAge)
+	assert.False(t, meta.Stale)
+	assert.Equal(t, int64(len(helloContent)), meta.Size)
+}
@@ -0,0 +1,94 @@
+package fetch
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// FetchMetaTags fetches an HTML document and returns its metadata found in
+// the head: the text of <title> under "title" key and the content of <meta>
+// tags under their lowercased name or property, e.g. "description" or
+// "og:image". The first of repeated tags wins. Reading of the body stops
+// at the end of the head. A response which is not HTML returns
+// errs.BadDocument.
+func (bf *BaseFetcher) FetchMetaTags(request Request) (map[string]string, *Response, error) {
+	resp, err := bf.FetchResponse(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Close()
+	contentType := resp.GetHeaders().Get("Content-Type")
+	if !isHTML(contentType) {
+		return nil, resp, &errs.BadDocument{URL: resp.GetURL(), Err: fmt.Errorf("not an HTML document: %q", contentType)}
+	}
+	utf8, err := charset.NewReader(resp, contentType)
+	if err != nil {
+		return nil, resp, &errs.BadDocument{URL: resp.GetURL(), Err: err}
+	}
+	tags, err := parseMetaTags(utf8)
+	if err != nil {
+		return nil, resp, err
+	}
+	return tags, resp, nil
+}
+
+// parseMetaTags returns the title and meta tags of the head of the HTML document r.
+func parseMetaTags(r io.Reader) (map[string]string, error) {
+	tags := map[string]string{}
+	set := func(key, value string) {
+		if _, ok := tags[key]; !ok && key != "" {
+			tags[key] = strings.TrimSpace(value)
+		}
+	}
+	z := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return tags, nil
+			}
+			return tags, z.Err()
+		case html.TextToken:
+			if inTitle {
+				set("title", string(z.Text()))
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "head":
+				return tags, nil
+			case "title":
+				inTitle = false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "body":
+				return tags, nil
+			case "title":
+				inTitle = true
+			case "meta":
+				var key, content string
+				for hasAttr {
+					var attr, val []byte
+					attr, val, hasAttr = z.TagAttr()
+					switch string(attr) {
+					case "name", "property":
+						if key == "" {
+							key = strings.ToLower(strings.TrimSpace(string(val)))
+						}
+					case "content":
+						content = string(val)
+					}
+				}
+				set(key, content)
+			}
+		}
+	}
+}
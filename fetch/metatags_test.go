@@ -0,0 +1,45 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_FetchMetaTags(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/image.png" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+		w.Write([]byte(`<!DOCTYPE html><html><head>
+<meta charset="iso-8859-1">
+<title> Caf` + "\xe9" + ` &amp; Bar </title>
+<meta name="Description" content="Best coffee in town">
+<meta property="og:title" content="Caf` + "\xe9" + `">
+<meta property="og:image" content="http://example.com/cafe.jpg" />
+<meta property="og:image" content="http://example.com/other.jpg" />
+</head><body><meta name="late" content="ignored"><h1>Menu</h1></body></html>`))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	tags, resp, err := fetcher.FetchMetaTags(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.GetStatusCode())
+	assert.Equal(t, map[string]string{
+		"title":       "Café & Bar",
+		"description": "Best coffee in town",
+		"og:title":    "Café",
+		"og:image":    "http://example.com/cafe.jpg",
+	}, tags)
+
+	_, _, err = fetcher.FetchMetaTags(Request{URL: ts.URL + "/image.png"})
+	assert.IsType(t, &errs.BadDocument{}, err)
+}
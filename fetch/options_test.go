@@ -0,0 +1,256 @@
+package fetch
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBaseFetcherWithOptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		http.SetCookie(w, &http.Cookie{Name: "ua", Value: url.QueryEscape(r.UserAgent())})
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	//no options
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	assert.Nil(t, fetcher.getCookieJar())
+	assert.Nil(t, fetcher.transport.Proxy)
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+
+	//user agent and cookie jar
+	jar, _ := cookiejar.New(nil)
+	fetcher, err = NewBaseFetcherWithOptions(
+		WithUserAgent("DataflowKitBot"),
+		WithCookieJar(NewCookieJar(jar)),
+	)
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	u, _ := url.Parse(ts.URL)
+	cookies := fetcher.getCookieJar().Cookies(u)
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, "DataflowKitBot", cookies[0].Value)
+	}
+
+	//timeout and proxy
+	fetcher, err = NewBaseFetcherWithOptions(
+		WithProxy("http://127.0.0.1:3128"),
+		WithTimeout(50*time.Millisecond),
+	)
+	assert.NoError(t, err)
+	assert.NotNil(t, fetcher.transport.Proxy)
+	assert.Equal(t, 50*time.Millisecond, fetcher.client.Timeout)
+	fetcher, err = NewBaseFetcherWithOptions(WithTimeout(50 * time.Millisecond))
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/slow"})
+	assert.Error(t, err)
+
+	//invalid proxy
+	_, err = NewBaseFetcherWithOptions(WithProxy("http://[::1]:namedport"))
+	assert.Error(t, err)
+}
+
+func TestBaseFetcher_UserTokenHeader(t *testing.T) {
+	var token string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-User-Token")
+	}))
+	defer ts.Close()
+
+	req := Request{URL: ts.URL, UserToken: "12345"}
+	//disabled by default
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "", token)
+
+	fetcher, err = NewBaseFetcherWithOptions(WithUserTokenHeader("X-User-Token"))
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "12345", token)
+}
+
+func TestBaseFetcher_MaxHeaders(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		for i := 0; i < 100; i++ {
+			w.Header().Add("X-Filler", strings.Repeat("x", 1024))
+		}
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, helloContent, fetchAll(t, fetcher, ts.URL))
+
+	fetcher, err = NewBaseFetcherWithOptions(WithMaxHeaderBytes(64 << 10))
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.IsType(t, &errs.BadRequest{}, err)
+
+	fetcher, err = NewBaseFetcherWithOptions(WithMaxHeaderCount(50))
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	if assert.IsType(t, &errs.BadRequest{}, err) {
+		assert.Contains(t, err.Error(), "exceeded 50 values")
+	}
+
+	//header bombs are not fetched again
+	policy := WithRetryPolicy(&RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	for _, limit := range []Option{WithMaxHeaderBytes(64 << 10), WithMaxHeaderCount(50)} {
+		fetcher, err = NewBaseFetcherWithOptions(limit, policy)
+		assert.NoError(t, err)
+		atomic.StoreInt32(&hits, 0)
+		_, err = fetcher.Fetch(Request{URL: ts.URL})
+		assert.IsType(t, &errs.BadRequest{}, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	}
+}
+
+func TestBaseFetcher_Accept(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Accept") + "|" + r.Header.Get("User-Agent")))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions(WithUserAgent("dfk"))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultAccept+"|dfk", string(fetchAll(t, fetcher, ts.URL)))
+
+	fetcher, err = NewBaseFetcherWithOptions(WithAccept("application/json"), WithUserAgent("dfk"))
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json|dfk", string(fetchAll(t, fetcher, ts.URL)))
+
+	//request headers take precedence
+	content, err := fetcher.Fetch(Request{URL: ts.URL, Headers: http.Header{
+		"accept":     {"text/csv"},
+		"User-Agent": {"custom"},
+	}})
+	if assert.NoError(t, err) {
+		data, _ := ioutil.ReadAll(content)
+		assert.Equal(t, "text/csv|custom", string(data))
+	}
+}
+
+func TestNewBaseFetcherFromClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://blocked.example.com/", http.StatusFound)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+	}))
+	defer ts.Close()
+
+	var traced []string
+	cJar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			traced = append(traced, req.URL.Path)
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+		Jar:     NewCookieJar(cJar),
+		Timeout: time.Second,
+	}
+	fetcher := NewBaseFetcherFromClient(client)
+	fetcher.BlockedHosts = []string{"blocked.example.com"}
+	content, err := fetcher.Fetch(Request{URL: ts.URL + "/login"})
+	assert.NoError(t, err)
+	content.Close()
+	assert.Equal(t, []string{"/login"}, traced)
+	cookies, err := fetcher.CookiesForURL(ts.URL)
+	assert.NoError(t, err)
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, time.Second, fetcher.client.Timeout)
+
+	//redirects are checked without modifying the client
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/redirect"})
+	assert.IsType(t, &errs.ForbiddenHost{}, err)
+	assert.Nil(t, client.CheckRedirect)
+}
+
+func TestWithDebugProxy(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+	//a tunneling proxy requiring credentials, standing in for mitmproxy
+	var tunneled []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "CONNECT" || r.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte("user:pass")) {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tunneled = append(tunneled, r.Host)
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+	}))
+	defer proxy.Close()
+	dir, err := ioutil.TempDir("", "ca")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	caPath := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	assert.NoError(t, ioutil.WriteFile(caPath, ca, 0644))
+	proxyURL := strings.Replace(proxy.URL, "http://", "http://user:pass@", 1)
+
+	fetcher, err := NewBaseFetcherWithOptions(WithDebugProxy(proxyURL, caPath))
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(Request{URL: ts.URL})
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(content)
+		assert.Equal(t, helloContent, body)
+		content.Close()
+	}
+	assert.Equal(t, []string{ts.Listener.Addr().String()}, tunneled)
+
+	//the CA is not trusted without the option
+	fetcher, err = NewBaseFetcherWithOptions(WithProxy(proxyURL))
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.Error(t, err)
+
+	_, err = NewBaseFetcherWithOptions(WithDebugProxy(proxyURL, filepath.Join(dir, "missing.pem")))
+	assert.Error(t, err)
+	assert.NoError(t, ioutil.WriteFile(caPath, []byte("not a certificate"), 0644))
+	_, err = NewBaseFetcherWithOptions(WithDebugProxy(proxyURL, caPath))
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package fetch
+
+import (
+	"net/url"
+	"strings"
+)
+
+// FetchAllPages fetches request and the pages following it as linked by
+// `Link: <...>; rel="next"` response headers (RFC 5988) until there is no
+// next page or maxPages pages are fetched. Zero maxPages means no limit.
+// Next pages are requested with GET and the other fields of request, cookies
+// are shared through the cookie jar of BaseFetcher. The page bodies are read
+// into memory so the connections are released.
+// If a page fails the pages fetched so far are returned along with the error.
+func (bf *BaseFetcher) FetchAllPages(request Request, maxPages int) ([]*Response, error) {
+	pages := []*Response{}
+	visited := make(map[string]bool)
+	for maxPages <= 0 || len(pages) < maxPages {
+		resp, err := bf.FetchResponse(request)
+		if err != nil {
+			return pages, err
+		}
+		if err := resp.buffer(); err != nil {
+			return pages, err
+		}
+		pages = append(pages, resp)
+		visited[resp.GetURL()] = true
+		next, ok := resp.GetLinks()["next"]
+		if !ok || visited[next] {
+			break
+		}
+		request.URL = next
+		request.Method = "GET"
+		request.FormData = ""
+	}
+	return pages, nil
+}
+
+// parseLinks returns the targets of Link header values by relation type.
+// Relative targets are resolved against base. The first link of a relation wins.
+func parseLinks(values []string, base *url.URL) map[string]string {
+	links := make(map[string]string)
+	for _, v := range values {
+		for {
+			v = strings.TrimLeft(v, " \t,")
+			if !strings.HasPrefix(v, "<") {
+				break
+			}
+			end := strings.IndexByte(v, '>')
+			if end < 0 {
+				break
+			}
+			target := v[1:end]
+			var rels []string
+			v, rels = parseLinkParams(v[end+1:])
+			u, err := base.Parse(target)
+			if err != nil {
+				continue
+			}
+			for _, rel := range rels {
+				if _, ok := links[rel]; !ok {
+					links[rel] = u.String()
+				}
+			}
+		}
+	}
+	return links
+}
+
+// parseLinkParams parses the parameters of a link up to the next link in v
+// and returns the rest of v and the relation types of the link.
+func parseLinkParams(v string) (string, []string) {
+	var rels []string
+	for {
+		v = strings.TrimLeft(v, " \t")
+		if !strings.HasPrefix(v, ";") {
+			return v, rels
+		}
+		v = strings.TrimLeft(v[1:], " \t")
+		end := strings.IndexAny(v, "=;,")
+		if end < 0 {
+			return "", rels
+		}
+		name := strings.ToLower(strings.TrimSpace(v[:end]))
+		if v[end] != '=' {
+			v = v[end:]
+			continue
+		}
+		v = strings.TrimLeft(v[end+1:], " \t")
+		var value string
+		if strings.HasPrefix(v, `"`) {
+			closing := strings.IndexByte(v[1:], '"')
+			if closing < 0 {
+				return "", rels
+			}
+			value, v = v[1:closing+1], v[closing+2:]
+		} else {
+			end = strings.IndexAny(v, ";,")
+			if end < 0 {
+				end = len(v)
+			}
+			value, v = strings.TrimSpace(v[:end]), v[end:]
+		}
+		if name == "rel" {
+			rels = append(rels, strings.Fields(strings.ToLower(value))...)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package fetch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_FetchAllPages(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+			page = 1
+		} else if _, err := r.Cookie("session"); err != nil {
+			http.Error(w, "no session", http.StatusForbidden)
+			return
+		}
+		if page < 3 {
+			w.Header().Add("Link", fmt.Sprintf(`</items?page=%d>; rel="next", </items?page=3>; rel="last"`, page+1))
+		}
+		fmt.Fprintf(w, "page %d", page)
+	}))
+	defer ts.Close()
+
+	jar, _ := cookiejar.New(nil)
+	fetcher, err := NewBaseFetcherWithOptions(WithCookieJar(NewCookieJar(jar)))
+	assert.NoError(t, err)
+	//unread pages must not hold the host slot
+	fetcher.MaxConcurrentPerHost = 1
+	pages, err := fetcher.FetchAllPages(Request{URL: ts.URL + "/items"}, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, pages, 3) {
+		for i, page := range pages {
+			data, err := ioutil.ReadAll(page)
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("page %d", i+1), string(data))
+			page.Close()
+		}
+		assert.Equal(t, ts.URL+"/items?page=3", pages[0].GetLinks()["last"])
+	}
+
+	pages, err = fetcher.FetchAllPages(Request{URL: ts.URL + "/items"}, 2)
+	assert.NoError(t, err)
+	assert.Len(t, pages, 2)
+}
+
+func TestParseLinks(t *testing.T) {
+	base, _ := url.Parse("http://example.com/api/items?page=2")
+	links := parseLinks([]string{
+		`<https://example.com/api/items?page=3>; rel="next", <?page=1>; rel="prev first"`,
+		`<http://example.com/a,b>;title="a; b, c";rel=alternate`,
+		`<http://example.com/other-next>; rel=next`,
+		`garbage`,
+	}, base)
+	assert.Equal(t, map[string]string{
+		"next":      "https://example.com/api/items?page=3",
+		"prev":      "http://example.com/api/items?page=1",
+		"first":     "http://example.com/api/items?page=1",
+		"alternate": "http://example.com/a,b",
+	}, links)
+}
@@ -0,0 +1,60 @@
+package fetch
+
+import "net/http"
+
+// BrowserProfile is a coherent set of request headers sent by a real browser
+// version: User-Agent, client hints, Accept and Sec-Fetch-* headers.
+// Bot detection cross-checks them, so they are more convincing than a
+// User-Agent alone. Accept-Encoding is not part of profiles as BaseFetcher
+// only advertises the encodings it decodes, see AdvertiseContentDecoders.
+type BrowserProfile struct {
+	Name    string
+	Headers http.Header
+}
+
+// BrowserProfiles are the profiles WithBrowserProfile selects from by
+// lower case name.
+var BrowserProfiles = map[string]*BrowserProfile{
+	"chrome": {
+		Name: "Chrome 120 on Windows",
+		Headers: http.Header{
+			"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"},
+			"Sec-Ch-Ua":                 {`"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`},
+			"Sec-Ch-Ua-Mobile":          {"?0"},
+			"Sec-Ch-Ua-Platform":        {`"Windows"`},
+			"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+			"Accept-Language":           {"en-US,en;q=0.9"},
+			"Upgrade-Insecure-Requests": {"1"},
+			"Sec-Fetch-Site":            {"none"},
+			"Sec-Fetch-Mode":            {"navigate"},
+			"Sec-Fetch-User":            {"?1"},
+			"Sec-Fetch-Dest":            {"document"},
+		},
+	},
+	"firefox": {
+		Name: "Firefox 121 on Windows",
+		Headers: http.Header{
+			//Firefox sends no client hints
+			"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"},
+			"Accept":                    {"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"},
+			"Accept-Language":           {"en-US,en;q=0.5"},
+			"Upgrade-Insecure-Requests": {"1"},
+			"Sec-Fetch-Site":            {"none"},
+			"Sec-Fetch-Mode":            {"navigate"},
+			"Sec-Fetch-User":            {"?1"},
+			"Sec-Fetch-Dest":            {"document"},
+		},
+	},
+}
+
+// setProfileHeaders sets the headers of BrowserProfile h doesn't have yet.
+func (bf *BaseFetcher) setProfileHeaders(h http.Header) {
+	if bf.BrowserProfile == nil {
+		return
+	}
+	for name, values := range bf.BrowserProfile.Headers {
+		if _, ok := h[name]; !ok {
+			h[name] = values
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_BrowserProfile(t *testing.T) {
+	var header http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer ts.Close()
+
+	fetch := func(fetcher *BaseFetcher, headers http.Header) {
+		content, err := fetcher.Fetch(Request{URL: ts.URL, Headers: headers})
+		if assert.NoError(t, err) {
+			content.Close()
+		}
+	}
+	fetcher, err := NewBaseFetcherWithOptions(WithUserAgent("dfk"), WithBrowserProfile("Chrome"))
+	assert.NoError(t, err)
+	fetch(fetcher, nil)
+	for name, values := range BrowserProfiles["chrome"].Headers {
+		assert.Equal(t, values, header[name], name)
+	}
+	assert.Equal(t, "gzip", header.Get("Accept-Encoding"))
+
+	//request headers take precedence
+	fetch(fetcher, http.Header{"Accept-Language": {"de-DE"}})
+	assert.Equal(t, "de-DE", header.Get("Accept-Language"))
+	assert.Equal(t, "?1", header.Get("Sec-Fetch-User"))
+
+	fetcher, err = NewBaseFetcherWithOptions(WithBrowserProfile("firefox"))
+	assert.NoError(t, err)
+	fetch(fetcher, nil)
+	assert.Contains(t, header.Get("User-Agent"), "Firefox/")
+	assert.Empty(t, header.Get("Sec-Ch-Ua"))
+
+	_, err = NewBaseFetcherWithOptions(WithBrowserProfile("netscape"))
+	assert.Error(t, err)
+}
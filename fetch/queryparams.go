@@ -0,0 +1,68 @@
+package fetch
+
+import (
+	"net/url"
+	"strings"
+)
+
+// TrackingQueryParams are query parameters added by analytics and ad
+// platforms which don't change the document. Assign it to
+// BaseFetcher.StripQueryParams, possibly extended or trimmed, to fetch URLs
+// without them.
+var TrackingQueryParams = []string{
+	"utm_*",
+	"fbclid",
+	"gclid",
+	"dclid",
+	"msclkid",
+	"yclid",
+	"mc_cid",
+	"mc_eid",
+	"_ga",
+	"_gl",
+	"igshid",
+	"ref_src",
+}
+
+// stripQueryParams returns rawurl without the query parameters matching one
+// of params. A param ending with "*" matches parameters with that prefix.
+// The order of the remaining parameters is kept.
+func stripQueryParams(rawurl string, params []string) string {
+	if len(params) == 0 {
+		return rawurl
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil || u.RawQuery == "" {
+		return rawurl
+	}
+	kept := []string{}
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		name := pair
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			name = pair[:i]
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !matchQueryParam(name, params) {
+			kept = append(kept, pair)
+		}
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	return u.String()
+}
+
+func matchQueryParam(name string, params []string) bool {
+	name = strings.ToLower(name)
+	for _, p := range params {
+		p = strings.ToLower(p)
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(name, p[:len(p)-1]) {
+				return true
+			}
+		} else if name == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripQueryParams(t *testing.T) {
+	for rawurl, expected := range map[string]string{
+		"http://example.com/a?utm_source=news&utm_medium=email&id=1&fbclid=abc": "http://example.com/a?id=1",
+		"http://example.com/a?b=2&UTM_Campaign=x&a=1&gclid=1#top":               "http://example.com/a?b=2&a=1#top",
+		"http://example.com/a?utm%5Fsource=x&q=a+b":                             "http://example.com/a?q=a+b",
+		"http://example.com/a?fbclid=abc":                                       "http://example.com/a",
+		"http://example.com/a?utmost=1&page=2":                                  "http://example.com/a?utmost=1&page=2",
+		"http://example.com/a":                                                  "http://example.com/a",
+	} {
+		assert.Equal(t, expected, stripQueryParams(rawurl, TrackingQueryParams), rawurl)
+	}
+	assert.Equal(t, "http://example.com/?utm_source=x",
+		stripQueryParams("http://example.com/?utm_source=x&session=1", []string{"session"}))
+	assert.Equal(t, "http://example.com/?fbclid=1", stripQueryParams("http://example.com/?fbclid=1", nil))
+}
+
+func TestBaseFetcher_StripQueryParams(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(r.URL.RawQuery))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.StripQueryParams = TrackingQueryParams
+	fetcher.SessionCache = NewSessionCache(10, time.Minute)
+	assert.Equal(t, "id=1", string(fetchAll(t, fetcher, ts.URL+"/?utm_source=a&id=1")))
+	assert.Equal(t, "id=1", string(fetchAll(t, fetcher, ts.URL+"/?id=1&gclid=2")))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&hits), "URLs differing by tracking params are fetched once")
+}
@@ -0,0 +1,210 @@
+package fetch
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// limitedReader stops reading of body once more than maxBytes bytes are
+// read or timeout has passed since it was created, whichever comes first.
+// A zero limit is not enforced. The bytes read before a limit trips are
+// returned along with errs.BodyTooLarge or errs.GatewayTimeout so callers
+// can still use the partial body. The body is closed when a limit trips,
+// on timeout this unblocks a pending Read of a server trickling bytes.
+type limitedReader struct {
+	body      io.ReadCloser
+	url       string
+	maxBytes  int64
+	remaining int64
+	tooLarge  bool
+	timer     *time.Timer
+	expired   int32
+}
+
+func newLimitedReader(body io.ReadCloser, url string, maxBytes int64, timeout time.Duration) *limitedReader {
+	r := &limitedReader{
+		body:      body,
+		url:       url,
+		maxBytes:  maxBytes,
+		remaining: maxBytes,
+	}
+	if timeout > 0 {
+		r.timer = time.AfterFunc(timeout, func() {
+			atomic.StoreInt32(&r.expired, 1)
+			body.Close()
+		})
+	}
+	return r
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.tooLarge {
+		return 0, &errs.BodyTooLarge{URL: r.url, Limit: r.maxBytes}
+	}
+	//read one byte over the limit to find out if the body exceeds it
+	if r.maxBytes > 0 && int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.body.Read(p)
+	if atomic.LoadInt32(&r.expired) == 1 {
+		return n, &errs.GatewayTimeout{}
+	}
+	if r.maxBytes > 0 {
+		if int64(n) > r.remaining {
+			n = int(r.remaining)
+			r.tooLarge = true
+			r.Close()
+			return n, &errs.BodyTooLarge{URL: r.url, Limit: r.maxBytes}
+		}
+		r.remaining -= int64(n)
+	}
+	if err == io.EOF {
+		r.stopTimer()
+	}
+	return n, err
+}
+
+func (r *limitedReader) Close() error {
+	r.stopTimer()
+	return r.body.Close()
+}
+
+func (r *limitedReader) stopTimer() {
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+}
+
+// readCloser combines a Reader with the Closer of the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// checkBodySize returns errs.EmptyResponse if the body of resp is shorter
+// than min bytes. A body of unknown length is read up to min bytes which
+// are put back in front of the rest of the body.
+func checkBodySize(resp *http.Response, min int64) error {
+	if resp.ContentLength >= 0 {
+		if resp.ContentLength < min {
+			resp.Body.Close()
+			return &errs.EmptyResponse{URL: resp.Request.URL.String()}
+		}
+		return nil
+	}
+	head := make([]byte, min)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		resp.Body.Close()
+		return err
+	}
+	if int64(n) < min {
+		resp.Body.Close()
+		return &errs.EmptyResponse{URL: resp.Request.URL.String()}
+	}
+	resp.Body = readCloser{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	return nil
+}
+
+// incompleteReader reports a body cut short, e.g. a truncated gzip stream or
+// a dropped connection, as errs.IncompleteRead. Content read before the
+// error is returned as usual so callers can use the partial body.
+type incompleteReader struct {
+	io.ReadCloser
+	url string
+}
+
+func (r incompleteReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err == io.ErrUnexpectedEOF {
+		err = &errs.IncompleteRead{URL: r.url}
+	}
+	return n, err
+}
+
+// transformingBody returns body changed by transform, e.g. with HTML
+// entities decoded. Changes may depend on content split across reads, so
+// body is read in full on the first Read. Content read before an error,
+// e.g. errs.BodyTooLarge, is transformed and returned before the error.
+type transformingBody struct {
+	body      io.ReadCloser
+	transform func([]byte) []byte
+	result    io.Reader
+	err       error
+}
+
+// transformBody makes the body of resp be changed by transform.
+func transformBody(resp *http.Response, transform func([]byte) []byte) {
+	resp.Body = &transformingBody{body: resp.Body, transform: transform}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+}
+
+func (r *transformingBody) Read(p []byte) (int, error) {
+	if r.result == nil {
+		content, err := ioutil.ReadAll(r.body)
+		r.result = bytes.NewReader(r.transform(content))
+		r.err = err
+	}
+	n, err := r.result.Read(p)
+	if err == io.EOF && r.err != nil {
+		err = r.err
+	}
+	return n, err
+}
+
+func (r *transformingBody) Close() error {
+	return r.body.Close()
+}
+
+// unescapeHTMLEntities decodes numeric and named HTML entities in content.
+func unescapeHTMLEntities(content []byte) []byte {
+	return []byte(html.UnescapeString(string(content)))
+}
+
+// wireBytesKey is the request context key of the number of body bytes
+// received for the request before decompression.
+type wireBytesKey struct{}
+
+// countingReader adds the number of bytes read to n.
+type countingReader struct {
+	io.ReadCloser
+	n *int64
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	*r.n += int64(n)
+	return n, err
+}
+
+// delayBodyRead waits a random time between BodyReadDelayMin and
+// BodyReadDelayMax before the body of resp is read. The wait ends early
+// with an error if the request is canceled.
+func (bf *BaseFetcher) delayBodyRead(resp *http.Response) error {
+	delay := bf.BodyReadDelayMin
+	if spread := bf.BodyReadDelayMax - bf.BodyReadDelayMin; spread > 0 {
+		delay += time.Duration(rand.Int63n(int64(spread) + 1))
+	}
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	ctx := resp.Request.Context()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		resp.Body.Close()
+		return clientError(resp.Request, ctx.Err())
+	}
+}
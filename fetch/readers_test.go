@@ -0,0 +1,232 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+//slowServer writes body in chunks pausing between them.
+func slowServer(chunks int, pause time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < chunks; i++ {
+			w.Write([]byte("chunk "))
+			w.(http.Flusher).Flush()
+			time.Sleep(pause)
+		}
+	}))
+}
+
+func TestBaseFetcher_BodyReadTimeout(t *testing.T) {
+	ts := slowServer(5, 50*time.Millisecond)
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions(WithBodyReadTimeout(100 * time.Millisecond))
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(Request{URL: ts.URL})
+	//headers arrive in time
+	assert.NoError(t, err)
+	start := time.Now()
+	data, err := ioutil.ReadAll(content)
+	assert.IsType(t, &errs.GatewayTimeout{}, err)
+	assert.True(t, strings.HasPrefix(string(data), "chunk "), "partial body is returned")
+	assert.True(t, time.Since(start) < 200*time.Millisecond)
+	content.Close()
+
+	fetcher, err = NewBaseFetcherWithOptions(WithBodyReadTimeout(time.Second))
+	assert.NoError(t, err)
+	content, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	data, err = ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "chunk chunk chunk chunk chunk ", string(data))
+	content.Close()
+
+	//body checks reading ahead are limited too
+	fetcher, err = NewBaseFetcherWithOptions(WithBodyReadTimeout(100 * time.Millisecond))
+	assert.NoError(t, err)
+	fetcher.BlockDetector, err = NewBlockDetector()
+	assert.NoError(t, err)
+	fetcher.RetryOnBodyPattern = regexp.MustCompile("busy")
+	start = time.Now()
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.IsType(t, &errs.GatewayTimeout{}, err)
+	assert.True(t, time.Since(start) < 200*time.Millisecond)
+}
+
+func TestBaseFetcher_RejectEmptyBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/empty":
+		case "/chunked":
+			//unknown content length
+			w.Write([]byte("short"))
+			w.(http.Flusher).Flush()
+		default:
+			w.Write(helloContent)
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/empty"})
+	assert.NoError(t, err, "empty body is accepted by default")
+
+	fetcher.RejectEmptyBody = true
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/empty"})
+	assert.IsType(t, &errs.EmptyResponse{}, err)
+	content, err := fetcher.Fetch(Request{URL: ts.URL + "/chunked"})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "short", string(data))
+
+	fetcher.MinBodySize = 10
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/chunked"})
+	assert.IsType(t, &errs.EmptyResponse{}, err)
+	content, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	data, err = ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, helloContent, data)
+}
+
+func TestBaseFetcher_TruncatedGzip(t *testing.T) {
+	content := strings.Repeat("<p>Hello World</p>", 1000)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(content))
+	zw.Close()
+	compressed := buf.Bytes()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		if r.URL.Path == "/truncated" {
+			w.Write(compressed[:len(compressed)/2])
+			return
+		}
+		w.Write(compressed)
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	body, err := fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(body)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+
+	body, err = fetcher.Fetch(Request{URL: ts.URL + "/truncated"})
+	assert.NoError(t, err)
+	data, err = ioutil.ReadAll(body)
+	assert.IsType(t, &errs.IncompleteRead{}, err)
+	assert.NotEmpty(t, data)
+	assert.True(t, strings.HasPrefix(content, string(data)), "decompressed prefix is returned")
+}
+
+func TestBaseFetcher_MaxBodyBytes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("a"), 10000))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions(WithMaxBodyBytes(100))
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	if assert.IsType(t, &errs.BodyTooLarge{}, err) {
+		assert.Equal(t, int64(100), err.(*errs.BodyTooLarge).Limit)
+	}
+	assert.Equal(t, bytes.Repeat([]byte("a"), 100), data)
+
+	//body of exactly the limit size
+	fetcher.MaxBodyBytes = 10000
+	content, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	data, err = ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Len(t, data, 10000)
+
+	//size limit trips before the time limit
+	slow := slowServer(5, 50*time.Millisecond)
+	defer slow.Close()
+	fetcher, err = NewBaseFetcherWithOptions(
+		WithMaxBodyBytes(8),
+		WithBodyReadTimeout(time.Second),
+	)
+	assert.NoError(t, err)
+	content, err = fetcher.Fetch(Request{URL: slow.URL})
+	assert.NoError(t, err)
+	data, err = ioutil.ReadAll(content)
+	assert.IsType(t, &errs.BodyTooLarge{}, err)
+	assert.Equal(t, "chunk ch", string(data))
+}
+
+func TestBaseFetcher_BodyReadDelay(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions(WithBodyReadDelay(50*time.Millisecond, 80*time.Millisecond))
+	assert.NoError(t, err)
+	start := time.Now()
+	content, err := fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	data, _ := ioutil.ReadAll(content)
+	content.Close()
+	took := time.Since(start)
+	assert.Equal(t, helloContent, data)
+	assert.True(t, took >= 50*time.Millisecond, took.String())
+	assert.True(t, took < 500*time.Millisecond, took.String())
+
+	//the delay ends with the request context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = fetcher.Fetch(Request{URL: ts.URL, Context: ctx})
+	assert.IsType(t, &errs.GatewayTimeout{}, err)
+}
+
+func TestBaseFetcher_UnescapeHTMLEntities(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<p>Caf&eacute; &amp; Bar &#8211; &#x263A; &copy;2018</p>"))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Caf&eacute; &amp; Bar &#8211; &#x263A; &copy;2018</p>", string(data))
+
+	fetcher.UnescapeHTMLEntities = true
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	data, err = ioutil.ReadAll(resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Café & Bar – ☺ ©2018</p>", string(data))
+	assert.Empty(t, resp.GetHeaders().Get("Content-Length"))
+
+	//the part of the body read before a limit trips is decoded too
+	fetcher.MaxBodyBytes = 16
+	content, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	data, err = ioutil.ReadAll(content)
+	assert.IsType(t, &errs.BodyTooLarge{}, err)
+	assert.Equal(t, "<p>Café &", string(data))
+}
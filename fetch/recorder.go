@@ -0,0 +1,159 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// RecordMode defines what RecordingFetcher does with a request.
+type RecordMode int
+
+const (
+	// Record replays recorded requests and fetches and records new ones.
+	Record RecordMode = iota
+	// Replay only replays recorded requests. Other requests fail without
+	// hitting the network.
+	Replay
+	// Passthrough fetches every request without recording it.
+	Passthrough
+)
+
+// RecordingFetcher wraps a Fetcher to record fetched documents to a cassette
+// file and replay them later without network access, e.g. to test parsers
+// deterministically. Requests are matched by method, normalized URL and form
+// data. Only successful fetches are recorded.
+//
+// RecordingFetcher is safe for concurrent use if the wrapped Fetcher is.
+type RecordingFetcher struct {
+	fetcher Fetcher
+	path    string
+	mode    RecordMode
+
+	mu           sync.Mutex
+	interactions map[string]*interaction
+}
+
+// cassette is the content of a cassette file.
+type cassette struct {
+	Interactions []*interaction `json:"interactions"`
+}
+
+// interaction is a recorded request and the document fetched.
+type interaction struct {
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+	FormData string `json:"formData,omitempty"`
+	Body     []byte `json:"body"`
+}
+
+// NewRecordingFetcher returns RecordingFetcher wrapping fetcher in mode and
+// loads the cassette file at path. The file is created on the first recorded
+// fetch, it has to exist in Replay mode.
+func NewRecordingFetcher(fetcher Fetcher, path string, mode RecordMode) (*RecordingFetcher, error) {
+	f := &RecordingFetcher{
+		fetcher:      fetcher,
+		path:         path,
+		mode:         mode,
+		interactions: make(map[string]*interaction),
+	}
+	if mode == Passthrough {
+		return f, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && mode == Record {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	for _, i := range c.Interactions {
+		f.interactions[interactionKey(i.Method, i.URL, i.FormData)] = i
+	}
+	return f, nil
+}
+
+// Fetch replays the recorded document of request or fetches it with the
+// wrapped Fetcher according to the mode.
+func (f *RecordingFetcher) Fetch(request Request) (io.ReadCloser, error) {
+	if f.mode == Passthrough {
+		return f.fetcher.Fetch(request)
+	}
+	method, url := requestMethod(request), normalizeURL(request.getURL())
+	key := interactionKey(method, url, request.FormData)
+	f.mu.Lock()
+	i, ok := f.interactions[key]
+	f.mu.Unlock()
+	if ok {
+		return ioutil.NopCloser(bytes.NewReader(i.Body)), nil
+	}
+	if f.mode == Replay {
+		return nil, &errs.Error{Err: "no recorded response for " + method + " " + url}
+	}
+	content, err := f.fetcher.Fetch(request)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	body, err := ioutil.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.record(key, &interaction{Method: method, URL: url, FormData: request.FormData, Body: body}); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(body)), nil
+}
+
+// record adds i to the cassette and saves the cassette file.
+func (f *RecordingFetcher) record(key string, i *interaction) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.interactions[key] = i
+	keys := make([]string, 0, len(f.interactions))
+	for key := range f.interactions {
+		keys = append(keys, key)
+	}
+	//sorted so that recording the same fetches gives the same file
+	sort.Strings(keys)
+	c := cassette{Interactions: make([]*interaction, 0, len(keys))}
+	for _, key := range keys {
+		c.Interactions = append(c.Interactions, f.interactions[key])
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f.path, data, 0644)
+}
+
+func interactionKey(method, url, formData string) string {
+	return method + " " + url + "\n" + formData
+}
+
+// CookiesForURL returns the cookies of the wrapped Fetcher.
+func (f *RecordingFetcher) CookiesForURL(u string) ([]*http.Cookie, error) {
+	return f.fetcher.CookiesForURL(u)
+}
+
+func (f *RecordingFetcher) getCookieJar() CookieJar {
+	return f.fetcher.getCookieJar()
+}
+
+func (f *RecordingFetcher) setCookieJar(jar CookieJar) {
+	f.fetcher.setCookieJar(jar)
+}
+
+// Static type assertion
+var _ Fetcher = &RecordingFetcher{}
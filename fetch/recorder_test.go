@@ -0,0 +1,63 @@
+package fetch
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordingFetcher(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		r.ParseForm()
+		w.Write([]byte(r.Method + " " + r.URL.Path + " " + r.PostForm.Get("q")))
+	}))
+	dir, err := ioutil.TempDir("", "dfk")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cassette.json")
+
+	base, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	get := func(f Fetcher, r Request) string {
+		content, err := f.Fetch(r)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		defer content.Close()
+		data, err := ioutil.ReadAll(content)
+		assert.NoError(t, err)
+		return string(data)
+	}
+
+	_, err = NewRecordingFetcher(base, path, Replay)
+	assert.Error(t, err, "replay needs a cassette")
+
+	recorder, err := NewRecordingFetcher(base, path, Record)
+	assert.NoError(t, err)
+	assert.Equal(t, "GET /a ", get(recorder, Request{URL: ts.URL + "/a"}))
+	assert.Equal(t, "POST /a 1", get(recorder, Request{URL: ts.URL + "/a", FormData: "q=1"}))
+	assert.Equal(t, "GET /a ", get(recorder, Request{URL: ts.URL + "/a"}))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&hits), "recorded requests are replayed")
+
+	passthrough, err := NewRecordingFetcher(base, path, Passthrough)
+	assert.NoError(t, err)
+	assert.Equal(t, "GET /a ", get(passthrough, Request{URL: ts.URL + "/a"}))
+	assert.EqualValues(t, 3, atomic.LoadInt32(&hits))
+
+	ts.Close()
+	replay, err := NewRecordingFetcher(base, path, Replay)
+	assert.NoError(t, err)
+	assert.Equal(t, "GET /a ", get(replay, Request{URL: ts.URL + "/a"}))
+	assert.Equal(t, "POST /a 1", get(replay, Request{URL: ts.URL + "/a", FormData: "q=1"}))
+	_, err = replay.Fetch(Request{URL: ts.URL + "/b"})
+	assert.Error(t, err)
+	assert.Len(t, replay.interactions, 2)
+}
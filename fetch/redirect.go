@@ -0,0 +1,43 @@
+package fetch
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// maxRedirects is the number of redirects followed before giving up.
+const maxRedirects = 10
+
+// checkRedirect is the CheckRedirect policy of BaseFetcher's http.Client.
+// Besides limiting the number of redirects it checks every redirect target
+// against the fetcher host restrictions.
+func (bf *BaseFetcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errors.New("stopped after 10 redirects")
+	}
+	if bf.SameOriginRedirectsOnly && !sameOrigin(req, via[0]) {
+		if bf.KeepCrossOriginRedirect {
+			return http.ErrUseLastResponse
+		}
+		return &errs.ForbiddenRedirect{URL: req.URL.String()}
+	}
+	bf.setKeepAlive(req)
+	bf.switchHostHeaders(req, via[len(via)-1])
+	return bf.checkHost(req.URL.Hostname())
+}
+
+// sameOrigin reports whether both requests have the same scheme and host.
+func sameOrigin(req, orig *http.Request) bool {
+	return strings.EqualFold(req.URL.Scheme, orig.URL.Scheme) &&
+		strings.EqualFold(req.URL.Host, orig.URL.Host)
+}
+
+// isKeptRedirect reports whether resp is a cross-origin redirect which
+// BaseFetcher has to return instead of following it.
+func (bf *BaseFetcher) isKeptRedirect(resp *http.Response) bool {
+	return bf.SameOriginRedirectsOnly && bf.KeepCrossOriginRedirect &&
+		resp.StatusCode/100 == 3 && resp.Header.Get("Location") != ""
+}
@@ -0,0 +1,66 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_SameOriginRedirectsOnly(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(helloContent)
+	}))
+	defer other.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, "/hello", http.StatusFound)
+		case "/cross":
+			http.Redirect(w, r, other.URL+"/hello", http.StatusFound)
+		default:
+			w.Write(helloContent)
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/cross"})
+	assert.NoError(t, err, "cross-origin redirects are followed by default")
+
+	fetcher.SameOriginRedirectsOnly = true
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL + "/same"})
+	assert.NoError(t, err)
+	assert.Equal(t, ts.URL+"/hello", resp.GetURL())
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/cross"})
+	if assert.IsType(t, &errs.ForbiddenRedirect{}, err) {
+		assert.Equal(t, other.URL+"/hello", err.(*errs.ForbiddenRedirect).URL)
+	}
+
+	fetcher.KeepCrossOriginRedirect = true
+	resp, err = fetcher.FetchResponse(Request{URL: ts.URL + "/cross"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.GetStatusCode())
+	assert.Equal(t, other.URL+"/hello", resp.GetHeaders().Get("Location"))
+}
+
+func TestBaseFetcher_RedirectWithoutLocation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusFound)
+		w.Write([]byte("moved somewhere"))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	_, err = fetcher.Fetch(Request{URL: ts.URL + "/moved"})
+	if assert.IsType(t, &errs.BadRedirect{}, err) {
+		e := err.(*errs.BadRedirect)
+		assert.Equal(t, http.StatusFound, e.Status)
+		assert.Equal(t, ts.URL+"/moved", e.URL)
+		assert.Equal(t, "moved somewhere", string(e.BodySnippet()))
+	}
+}
@@ -0,0 +1,317 @@
+package fetch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Response is a document fetched by BaseFetcher. It streams the body of the
+// HTTP response it was read from and gives access to the response metadata.
+// Response is returned by BaseFetcher.Fetch as io.ReadCloser so it can be
+// type asserted by callers interested in more than the content.
+type Response struct {
+	resp *http.Response
+	body io.Reader
+	// hash accumulates the body as it is read until the content hash is computed.
+	hash        hash.Hash
+	contentHash string
+	cache       cacheStatus
+	// wire is the number of body bytes received, decoded the number of body bytes read.
+	wire    *int64
+	decoded int64
+	// attempts are the attempts made to send the request.
+	attempts *[]Attempt
+	// seen are the content hashes Duplicate checks against, duplicate its result.
+	seen      *ContentHashes
+	checked   bool
+	duplicate bool
+}
+
+// newResponse wraps resp. newHash is used to compute the content hash,
+// SHA-256 is used if it is nil.
+func newResponse(resp *http.Response, newHash func() hash.Hash) *Response {
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	r := &Response{
+		resp: resp,
+		body: resp.Body,
+		hash: newHash(),
+	}
+	if resp.Request != nil {
+		r.wire, _ = resp.Request.Context().Value(wireBytesKey{}).(*int64)
+		r.attempts, _ = resp.Request.Context().Value(attemptsKey{}).(*[]Attempt)
+	}
+	return r
+}
+
+// Read reads the response body.
+func (r *Response) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.decoded += int64(n)
+	if r.hash != nil {
+		r.hash.Write(p[:n])
+	}
+	return n, err
+}
+
+// Close closes the response body.
+func (r *Response) Close() error {
+	return r.resp.Body.Close()
+}
+
+// GetURL returns the final URL of the document after redirects.
+func (r *Response) GetURL() string {
+	return r.resp.Request.URL.String()
+}
+
+// GetStatusCode returns HTTP status code of the response.
+func (r *Response) GetStatusCode() int {
+	return r.resp.StatusCode
+}
+
+// GetHeaders returns HTTP response headers.
+func (r *Response) GetHeaders() http.Header {
+	return r.resp.Header
+}
+
+// SetCookie is a cookie set by a response of the redirect chain of a document.
+type SetCookie struct {
+	// URL is the URL of the response setting the cookie.
+	URL    string
+	Cookie *http.Cookie
+}
+
+// GetCookieHistory returns the cookies set by the redirect responses leading
+// to the document and by the document itself in the order they were
+// received. The cookie jar applies them in the same order, so if a cookie is
+// set several times the last value is the one stored. It is meant to debug
+// authentication handshakes.
+func (r *Response) GetCookieHistory() []SetCookie {
+	var history []SetCookie
+	for _, hop := range r.hops() {
+		for _, c := range hop.Cookies() {
+			history = append(history, SetCookie{URL: hop.Request.URL.String(), Cookie: c})
+		}
+	}
+	return history
+}
+
+// GetRedirectChain returns the redirect responses followed to fetch the
+// document in the order they were received, followed by r itself, e.g. to
+// audit the status and headers of every hop. The redirect responses have
+// an empty body.
+func (r *Response) GetRedirectChain() []*Response {
+	hops := r.hops()
+	chain := make([]*Response, 0, len(hops))
+	for _, hop := range hops[:len(hops)-1] {
+		redirect := *hop
+		redirect.Body = http.NoBody
+		chain = append(chain, &Response{resp: &redirect, body: redirect.Body})
+	}
+	return append(chain, r)
+}
+
+// hops returns the responses of the redirect chain ending with the document
+// in the order they were received. http.Client links every request of the
+// chain to the redirect response which caused it.
+func (r *Response) hops() []*http.Response {
+	var hops []*http.Response
+	for resp := r.resp; resp != nil; resp = resp.Request.Response {
+		hops = append([]*http.Response{resp}, hops...)
+		if resp.Request == nil {
+			break
+		}
+	}
+	return hops
+}
+
+// GetLinks returns the targets of the Link response headers by relation
+// type, e.g. "next", resolved against the response URL.
+func (r *Response) GetLinks() map[string]string {
+	return parseLinks(r.resp.Header["Link"], r.resp.Request.URL)
+}
+
+// GetTrailers returns HTTP trailers sent after the response body. Trailers
+// are only available once the body has been read till the end.
+func (r *Response) GetTrailers() http.Header {
+	return r.resp.Trailer
+}
+
+// GetProtocol returns the protocol the response was received over, e.g. "HTTP/1.1" or "HTTP/2.0".
+func (r *Response) GetProtocol() string {
+	return r.resp.Proto
+}
+
+// GetWireBytes returns the number of body bytes received from the server
+// so far, i.e. before decompression. Responses served from
+// BaseFetcher.SessionCache report 0.
+func (r *Response) GetWireBytes() int64 {
+	if r.wire == nil {
+		return 0
+	}
+	return *r.wire
+}
+
+// GetAttempts returns the attempts made to send the request, including
+// failed ones retried according to the retry policy, the last one being
+// the successful attempt. Responses served from BaseFetcher.SessionCache
+// report none.
+func (r *Response) GetAttempts() []Attempt {
+	if r.attempts == nil {
+		return nil
+	}
+	return *r.attempts
+}
+
+// GetDecodedBytes returns the number of body bytes read so far after
+// decompression. Both counts are final once the body is read till the end.
+func (r *Response) GetDecodedBytes() int64 {
+	return r.decoded
+}
+
+// Stale reports whether the document is an expired copy from
+// BaseFetcher.SessionCache served because the host couldn't be connected,
+// see BaseFetcher.OfflineFallback.
+func (r *Response) Stale() bool {
+	return r.cache.stale
+}
+
+// FromCache reports whether the document was served by
+// BaseFetcher.SessionCache and returns the time since it was fetched.
+func (r *Response) FromCache() (bool, time.Duration) {
+	return r.cache.hit, r.cache.age
+}
+
+// GetCanonicalURL returns the absolute URL of the <link rel="canonical">
+// element in the head of an HTML document. It reports false for other
+// content types and documents without a canonical link. The body is
+// buffered to be parsed, so GetCanonicalURL has to be called before the
+// head is read; the content stays available to Read.
+func (r *Response) GetCanonicalURL() (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(r.resp.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return "", false
+	}
+	rest, err := ioutil.ReadAll(r.body)
+	r.body = bytes.NewReader(rest)
+	if err != nil {
+		r.body = io.MultiReader(r.body, errReader{err})
+	}
+	return parseCanonicalURL(rest, r.resp.Request.URL)
+}
+
+// parseCanonicalURL returns the href of the first canonical link in the
+// head of doc resolved against base.
+func parseCanonicalURL(doc []byte, base *url.URL) (string, bool) {
+	z := html.NewTokenizer(bytes.NewReader(doc))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return "", false
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "head" {
+				return "", false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if string(name) == "body" {
+				return "", false
+			}
+			if string(name) != "link" || !hasAttr {
+				continue
+			}
+			var rel, href string
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				switch string(key) {
+				case "rel":
+					rel = string(val)
+				case "href":
+					href = strings.TrimSpace(string(val))
+				}
+			}
+			if href == "" || !hasToken(rel, "canonical") {
+				continue
+			}
+			u, err := base.Parse(href)
+			if err != nil {
+				return "", false
+			}
+			return u.String(), true
+		}
+	}
+}
+
+// hasToken reports whether the space separated list contains token ignoring case.
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetContentHash returns hex encoded hash of the whole response body.
+// The hash is computed while the body is read. If the body has not been read
+// till the end yet the rest of it is buffered so it is still available to Read.
+func (r *Response) GetContentHash() string {
+	if r.hash == nil {
+		return r.contentHash
+	}
+	rest, err := ioutil.ReadAll(r.body)
+	r.hash.Write(rest)
+	r.body = bytes.NewReader(rest)
+	if err != nil {
+		r.body = io.MultiReader(r.body, errReader{err})
+	}
+	r.contentHash = hex.EncodeToString(r.hash.Sum(nil))
+	r.hash = nil
+	return r.contentHash
+}
+
+// Duplicate reports whether a document with the same content was fetched
+// before, according to BaseFetcher.ContentHashes. The content hash is
+// computed and recorded on the first call, see GetContentHash. Duplicate
+// returns false if ContentHashes is not set.
+func (r *Response) Duplicate() bool {
+	if r.seen == nil {
+		return false
+	}
+	if !r.checked {
+		r.duplicate = r.seen.Seen(r.GetContentHash())
+		r.checked = true
+	}
+	return r.duplicate
+}
+
+// buffer reads the rest of the body into memory and closes the connection.
+// The content stays available to Read.
+func (r *Response) buffer() error {
+	rest, err := ioutil.ReadAll(r.body)
+	r.body = bytes.NewReader(rest)
+	r.resp.Body.Close()
+	return err
+}
+
+// errReader returns err on every Read.
+type errReader struct {
+	err error
+}
+
+func (e errReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
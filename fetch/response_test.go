@@ -0,0 +1,275 @@
+package fetch
+
+import (
+	"crypto/md5"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+)
+
+func TestResponse_GetContentHash(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/other" {
+			w.Write([]byte("other content"))
+			return
+		}
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	fetcher := newBaseFetcher()
+	//hash of a fully read body
+	resp1, err := fetcher.FetchResponse(Request{URL: ts.URL + "/hello"})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(resp1)
+	assert.NoError(t, err)
+	assert.Equal(t, helloContent, data)
+	hash1 := resp1.GetContentHash()
+	assert.Len(t, hash1, 64)
+
+	//hash computed before the body is read
+	resp2, err := fetcher.FetchResponse(Request{URL: ts.URL + "/mirror"})
+	assert.NoError(t, err)
+	assert.Equal(t, hash1, resp2.GetContentHash())
+	data, err = ioutil.ReadAll(resp2)
+	assert.NoError(t, err)
+	assert.Equal(t, helloContent, data, "body is still available after hashing")
+	assert.Equal(t, hash1, resp2.GetContentHash())
+
+	resp3, err := fetcher.FetchResponse(Request{URL: ts.URL + "/other"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash1, resp3.GetContentHash())
+
+	//custom hash algorithm
+	fetcher.HashFunc = md5.New
+	resp4, err := fetcher.FetchResponse(Request{URL: ts.URL + "/hello"})
+	assert.NoError(t, err)
+	assert.Len(t, resp4.GetContentHash(), 32)
+}
+
+func TestResponse_GetProtocol(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(helloContent)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	tlsServer := httptest.NewUnstartedServer(handler)
+	assert.NoError(t, http2.ConfigureServer(tlsServer.Config, nil))
+	tlsServer.TLS = tlsServer.Config.TLSConfig
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+	fetcher.transport.TLSClientConfig = &tls.Config{
+		RootCAs:    roots,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	for url, proto := range map[string]string{
+		ts.URL:        "HTTP/1.1",
+		tlsServer.URL: "HTTP/2.0",
+	} {
+		resp, err := fetcher.FetchResponse(Request{URL: url})
+		if assert.NoError(t, err, url) {
+			assert.Equal(t, proto, resp.GetProtocol(), url)
+			resp.Close()
+		}
+	}
+}
+
+func TestResponse_GetTrailers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write(helloContent)
+		w.(http.Flusher).Flush()
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Close()
+	assert.Empty(t, resp.GetTrailers().Get("Grpc-Status"), "trailers are not available before the body is read")
+	data, err := ioutil.ReadAll(resp)
+	assert.NoError(t, err)
+	assert.Equal(t, helloContent, data)
+	assert.Equal(t, "0", resp.GetTrailers().Get("Grpc-Status"))
+}
+
+func TestResponse_GetCookieHistory(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "token", Value: "1", Path: "/"})
+			http.Redirect(w, r, "/sso", http.StatusFound)
+		case "/sso":
+			http.SetCookie(w, &http.Cookie{Name: "token", Value: "2", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "sso", Value: "ok", Path: "/"})
+			http.Redirect(w, r, "/home", http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+
+	cJar, _ := cookiejar.New(nil)
+	fetcher, err := NewBaseFetcherWithOptions(WithCookieJar(NewCookieJar(cJar)))
+	assert.NoError(t, err)
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL + "/login"})
+	assert.NoError(t, err)
+	resp.Close()
+
+	var history []string
+	for _, c := range resp.GetCookieHistory() {
+		history = append(history, c.URL+" "+c.Cookie.Name+"="+c.Cookie.Value)
+	}
+	assert.Equal(t, []string{
+		ts.URL + "/login token=1",
+		ts.URL + "/sso token=2",
+		ts.URL + "/sso sso=ok",
+	}, history)
+
+	//the last value wins
+	cookies, err := fetcher.CookiesForURL(ts.URL + "/home")
+	assert.NoError(t, err)
+	values := map[string]string{}
+	for _, c := range cookies {
+		values[c.Name] = c.Value
+	}
+	assert.Equal(t, map[string]string{"token": "2", "sso": "ok"}, values)
+	assert.Len(t, fetcher.getCookieJar().AllCookies(), 2)
+}
+
+func TestResponse_GetWireBytes(t *testing.T) {
+	compressed := gzipData(helloContent)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" {
+			w.Write(helloContent)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed)
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.SessionCache = NewSessionCache(10, 0)
+	for _, path := range []string{"/gzip", "/plain"} {
+		resp, err := fetcher.FetchResponse(Request{URL: ts.URL + path})
+		assert.NoError(t, err)
+		data, err := ioutil.ReadAll(resp)
+		assert.NoError(t, err)
+		resp.Close()
+		assert.Equal(t, helloContent, data)
+		assert.EqualValues(t, len(helloContent), resp.GetDecodedBytes(), path)
+		if path == "/gzip" {
+			assert.EqualValues(t, len(compressed), resp.GetWireBytes(), path)
+		} else {
+			assert.EqualValues(t, len(helloContent), resp.GetWireBytes(), path)
+		}
+	}
+	//nothing is received for cached responses
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL + "/gzip"})
+	assert.NoError(t, err)
+	ioutil.ReadAll(resp)
+	resp.Close()
+	assert.EqualValues(t, 0, resp.GetWireBytes())
+	assert.EqualValues(t, len(helloContent), resp.GetDecodedBytes())
+}
+
+func TestResponse_GetCanonicalURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/product":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<html><head><title>Product</title><link rel="stylesheet" href="/a.css"><LINK REL="Canonical" HREF="/products/1"/></head><body>product</body></html>`))
+		case "/body":
+			//canonical links outside the head don't count
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>No canonical</title></head><body><link rel="canonical" href="/other"></body></html>`))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"link": "<link rel=\"canonical\" href=\"/other\">"}`))
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL + "/product?utm_source=feed"})
+	assert.NoError(t, err)
+	canonical, ok := resp.GetCanonicalURL()
+	assert.True(t, ok)
+	assert.Equal(t, ts.URL+"/products/1", canonical)
+	//the body is still there
+	data, _ := ioutil.ReadAll(resp)
+	resp.Close()
+	assert.Contains(t, string(data), "<body>product</body>")
+
+	for _, path := range []string{"/body", "/json"} {
+		resp, err := fetcher.FetchResponse(Request{URL: ts.URL + path})
+		assert.NoError(t, err)
+		_, ok := resp.GetCanonicalURL()
+		assert.False(t, ok, path)
+		resp.Close()
+	}
+}
+
+func TestResponse_GetRedirectChain(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			w.Header().Set("X-Hop", "1")
+			http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+		case "/moved":
+			w.Header().Set("X-Hop", "2")
+			http.Redirect(w, r, "/new", http.StatusFound)
+		default:
+			w.Write(helloContent)
+		}
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL + "/old"})
+	assert.NoError(t, err)
+	defer resp.Close()
+	chain := resp.GetRedirectChain()
+	if !assert.Len(t, chain, 3) {
+		return
+	}
+	assert.Equal(t, ts.URL+"/old", chain[0].GetURL())
+	assert.Equal(t, http.StatusMovedPermanently, chain[0].GetStatusCode())
+	assert.Equal(t, "1", chain[0].GetHeaders().Get("X-Hop"))
+	assert.Equal(t, ts.URL+"/moved", chain[1].GetURL())
+	assert.Equal(t, http.StatusFound, chain[1].GetStatusCode())
+	assert.Equal(t, "/new", chain[1].GetHeaders().Get("Location"))
+	body, err := ioutil.ReadAll(chain[1])
+	assert.NoError(t, err)
+	assert.Empty(t, body)
+
+	assert.Equal(t, resp, chain[2])
+	assert.Equal(t, ts.URL+"/new", chain[2].GetURL())
+	body, _ = ioutil.ReadAll(chain[2])
+	assert.Equal(t, helloContent, body)
+
+	//a document fetched without redirects is its own chain
+	resp, err = fetcher.FetchResponse(Request{URL: ts.URL + "/new"})
+	assert.NoError(t, err)
+	resp.Close()
+	assert.Equal(t, []*Response{resp}, resp.GetRedirectChain())
+}
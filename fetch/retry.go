@@ -0,0 +1,207 @@
+package fetch
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// JitterStrategy defines how retry delays are randomized to avoid
+// synchronized retries of many workers hitting the same host.
+type JitterStrategy int
+
+// Jitter strategies
+const (
+	// JitterNone uses the computed exponential delay as is.
+	JitterNone JitterStrategy = iota
+	// JitterFull picks a random delay in [0, computed].
+	JitterFull
+	// JitterEqual keeps half of the computed delay and randomizes the other half,
+	// i.e. picks a random delay in [computed/2, computed].
+	JitterEqual
+)
+
+// RetryPolicy defines how BaseFetcher retries failed requests using
+// exponential backoff.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles with every
+	// next retry up to MaxDelay.
+	BaseDelay time.Duration
+	// MaxDelay caps the computed delay. Zero means no cap.
+	MaxDelay time.Duration
+	Jitter   JitterStrategy
+	// MaxTotalDuration caps the time spent on all attempts including the
+	// delays between them. No retry is made which would start after the
+	// budget is exhausted, the last error is returned instead. Zero means
+	// attempts are only limited by MaxAttempts.
+	MaxTotalDuration time.Duration
+	// Retryable reports whether a request failed with err is worth retrying.
+	// If it is nil connection errors, 5xx statuses and bodies matching
+	// BaseFetcher.RetryOnBodyPattern are retried.
+	Retryable func(err error) bool
+}
+
+// Delay returns the time to wait before the retry following the given
+// number of failed attempts.
+func (p *RetryPolicy) Delay(failed int) time.Duration {
+	if failed < 1 {
+		failed = 1
+	}
+	delay := p.BaseDelay
+	for i := 1; i < failed && (p.MaxDelay == 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	switch p.Jitter {
+	case JitterFull:
+		return time.Duration(rand.Int63n(int64(delay) + 1))
+	case JitterEqual:
+		half := delay / 2
+		return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+	}
+	return delay
+}
+
+// retryable reports whether err is worth retrying according to the policy.
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	switch e := err.(type) {
+	case *errs.BadRequest:
+		//connection errors. 400 status responses carry no Err
+		_, headerLimit := e.Err.(*headerLimitError)
+		return e.Err != nil && !headerLimit
+	case *errs.InternalServerError,
+		*errs.BadGateway,
+		*errs.GatewayTimeout,
+		*errs.ServiceUnavailable,
+		*errs.ConnectionTimeout,
+		*errs.Error:
+		return true
+	}
+	return false
+}
+
+// Attempt describes an attempt to send a request made by BaseFetcher.
+type Attempt struct {
+	// Status is the status of the response, 0 if there was none.
+	Status int
+	// Err is the error the attempt failed with.
+	Err      error
+	Duration time.Duration
+	// Backoff is the delay before the next attempt.
+	Backoff time.Duration
+}
+
+// attemptsKey is the request context key of the attempts made to send the request.
+type attemptsKey struct{}
+
+// doRequest sends req, retrying it according to policy. Nil policy means no retries.
+// A request with a body is retried only if its body can be rewound. The
+// attempts are recorded in the request context if it has attemptsKey.
+func (bf *BaseFetcher) doRequest(req *http.Request, policy *RetryPolicy) (*http.Response, error) {
+	attempts, _ := req.Context().Value(attemptsKey{}).(*[]Attempt)
+	if attempts == nil {
+		attempts = new([]Attempt)
+	}
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		*attempts = append(*attempts, Attempt{})
+		current := &(*attempts)[len(*attempts)-1]
+		begin := time.Now()
+		resp, err := bf.send(req, current)
+		if err == nil && bf.BodyReadTimeout > 0 {
+			//started before any of the body is read, e.g. by checkRetryBody
+			resp.Body = newLimitedReader(resp.Body, resp.Request.URL.String(), 0, bf.BodyReadTimeout)
+		}
+		if err == nil && bf.RetryOnBodyPattern != nil {
+			if err = bf.checkRetryBody(resp); err != nil {
+				resp = nil
+			}
+		}
+		current.Err = err
+		current.Duration = time.Since(begin)
+		if err == nil || policy == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return resp, err
+		}
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req.Body = body
+		}
+		delay := policy.Delay(attempt)
+		if policy.MaxTotalDuration > 0 && time.Since(start)+delay >= policy.MaxTotalDuration {
+			return resp, err
+		}
+		current.Backoff = delay
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return resp, err
+		}
+	}
+}
+
+// checkRetryBody returns errs.ServiceUnavailable if the beginning of the
+// body of the successful resp matches RetryOnBodyPattern. The body is
+// decoded for matching, resp is left encoded for BaseFetcher.response to
+// decompress. The inspected beginning stays available to read.
+func (bf *BaseFetcher) checkRetryBody(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	head := make([]byte, defaultBlockMaxBytes)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		resp.Body.Close()
+		return clientError(resp.Request, err)
+	}
+	head = head[:n]
+	decoded := &http.Response{
+		Header:  cloneHeader(resp.Header),
+		Body:    ioutil.NopCloser(bytes.NewReader(head)),
+		Request: resp.Request,
+	}
+	var content []byte
+	if bf.decompress(decoded) == nil {
+		//the head of a compressed body decodes partially
+		content, _ = ioutil.ReadAll(io.LimitReader(decoded.Body, defaultBlockMaxBytes))
+		decoded.Body.Close()
+	}
+	if bf.RetryOnBodyPattern.Match(content) {
+		resp.Body.Close()
+		return &errs.ServiceUnavailable{
+			URL:     resp.Request.URL.String(),
+			Snippet: errs.Snippet{Body: bf.readSnippet(bytes.NewReader(content))},
+		}
+	}
+	resp.Body = readCloser{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	return nil
+}
+
+// cloneHeader returns a deep copy of h.
+func cloneHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
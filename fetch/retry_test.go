@@ -0,0 +1,210 @@
+package fetch
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+//flakyServer fails the first failures requests with status and then succeeds.
+func flakyServer(failures int32, status int) (*httptest.Server, *int32) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	return ts, &hits
+}
+
+func TestBaseFetcher_RetryPolicy(t *testing.T) {
+	ts, hits := flakyServer(2, http.StatusInternalServerError)
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions(WithRetryPolicy(&RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+	}))
+	assert.NoError(t, err)
+	//form data is sent again with every attempt
+	content, err := fetcher.Fetch(Request{URL: ts.URL, FormData: "a=b"})
+	assert.NoError(t, err)
+	data, _ := ioutil.ReadAll(content)
+	assert.Equal(t, "a=b", string(data))
+	assert.Equal(t, int32(3), atomic.LoadInt32(hits))
+
+	ts2, hits2 := flakyServer(5, http.StatusInternalServerError)
+	defer ts2.Close()
+	_, err = fetcher.Fetch(Request{URL: ts2.URL})
+	assert.IsType(t, &errs.InternalServerError{}, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(hits2))
+
+	//not found is not retried
+	ts3, hits3 := flakyServer(5, http.StatusNotFound)
+	defer ts3.Close()
+	_, err = fetcher.Fetch(Request{URL: ts3.URL})
+	assert.IsType(t, &errs.NotFound{}, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits3))
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	assert.Equal(t, 100*time.Millisecond, policy.Delay(1))
+	assert.Equal(t, 200*time.Millisecond, policy.Delay(2))
+	assert.Equal(t, 400*time.Millisecond, policy.Delay(3))
+	assert.Equal(t, time.Second, policy.Delay(5))
+	assert.Equal(t, time.Second, policy.Delay(100))
+
+	const samples = 1000
+	computed := 400 * time.Millisecond
+	for _, tc := range []struct {
+		jitter   JitterStrategy
+		min, max time.Duration
+	}{
+		{JitterFull, 0, computed},
+		{JitterEqual, computed / 2, computed},
+	} {
+		policy.Jitter = tc.jitter
+		var sum, lowest, highest time.Duration
+		lowest = computed
+		for i := 0; i < samples; i++ {
+			d := policy.Delay(3)
+			assert.True(t, d >= tc.min && d <= tc.max, "delay %v out of range", d)
+			sum += d
+			if d < lowest {
+				lowest = d
+			}
+			if d > highest {
+				highest = d
+			}
+		}
+		//delays are spread over the whole range
+		mean := sum / samples
+		middle := (tc.min + tc.max) / 2
+		spread := tc.max - tc.min
+		assert.InDelta(t, float64(middle), float64(mean), float64(spread)/10)
+		assert.True(t, lowest < tc.min+spread/10)
+		assert.True(t, highest > tc.max-spread/10)
+	}
+}
+
+func TestRetryPolicy_MaxTotalDuration(t *testing.T) {
+	ts, hits := flakyServer(10, http.StatusBadGateway)
+	defer ts.Close()
+
+	//delays of 40, 80, 160ms: the third retry would start past the budget
+	fetcher, err := NewBaseFetcherWithOptions(WithRetryPolicy(&RetryPolicy{
+		MaxAttempts:      10,
+		BaseDelay:        40 * time.Millisecond,
+		MaxTotalDuration: 200 * time.Millisecond,
+	}))
+	assert.NoError(t, err)
+	start := time.Now()
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.IsType(t, &errs.BadGateway{}, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(hits))
+	assert.True(t, time.Since(start) < 200*time.Millisecond)
+}
+
+func TestRequest_RetryPolicy(t *testing.T) {
+	fetcher, err := NewBaseFetcherWithOptions(WithRetryPolicy(&RetryPolicy{MaxAttempts: 3}))
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		policy *RetryPolicy
+		hits   int32
+	}{
+		{"fetcher default", nil, 3},
+		{"retries disabled", &RetryPolicy{MaxAttempts: 1}, 1},
+		{"more attempts", &RetryPolicy{MaxAttempts: 5}, 5},
+	} {
+		ts, hits := flakyServer(10, http.StatusInternalServerError)
+		_, err = fetcher.Fetch(Request{URL: ts.URL, FormData: "a=b", RetryPolicy: tc.policy})
+		assert.Error(t, err, tc.name)
+		assert.Equal(t, tc.hits, atomic.LoadInt32(hits), tc.name)
+		ts.Close()
+	}
+
+	ts, hits := flakyServer(1, http.StatusInternalServerError)
+	defer ts.Close()
+	fetcher.RetryPolicy = nil
+	_, err = fetcher.Fetch(Request{URL: ts.URL, RetryPolicy: &RetryPolicy{MaxAttempts: 2}})
+	assert.NoError(t, err, "a request policy works without fetcher default")
+	assert.Equal(t, int32(2), atomic.LoadInt32(hits))
+}
+
+func TestBaseFetcher_RetryOnBodyPattern(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := "<html><body>article</body></html>"
+		if atomic.AddInt32(&hits, 1) == 1 {
+			body = "<html><body>Service temporarily unavailable, try again</body></html>"
+		}
+		//the pattern matches compressed bodies too
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(body))
+		zw.Close()
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions(
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+		WithRetryOnBodyPattern(`(?i)temporarily unavailable`),
+	)
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><body>article</body></html>", string(data))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+
+	//without retries the soft error is returned
+	atomic.StoreInt32(&hits, 0)
+	fetcher.RetryPolicy = nil
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	if assert.IsType(t, &errs.ServiceUnavailable{}, err) {
+		assert.Contains(t, string(err.(*errs.ServiceUnavailable).BodySnippet()), "temporarily unavailable")
+	}
+
+	_, err = NewBaseFetcherWithOptions(WithRetryOnBodyPattern(`(`))
+	assert.Error(t, err)
+}
+
+func TestResponse_GetAttempts(t *testing.T) {
+	ts, _ := flakyServer(2, http.StatusBadGateway)
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions(WithRetryPolicy(&RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   10 * time.Millisecond,
+	}))
+	assert.NoError(t, err)
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	resp.Close()
+	attempts := resp.GetAttempts()
+	if assert.Len(t, attempts, 3) {
+		for i, status := range []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK} {
+			assert.Equal(t, status, attempts[i].Status)
+			assert.True(t, attempts[i].Duration > 0)
+		}
+		assert.IsType(t, &errs.BadGateway{}, attempts[0].Err)
+		assert.Equal(t, 10*time.Millisecond, attempts[0].Backoff)
+		assert.Equal(t, 20*time.Millisecond, attempts[1].Backoff)
+		assert.NoError(t, attempts[2].Err)
+		assert.Equal(t, time.Duration(0), attempts[2].Backoff)
+	}
+}
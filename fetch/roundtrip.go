@@ -0,0 +1,202 @@
+package fetch
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// RoundTripperMiddleware wraps an http.RoundTripper sending requests of
+// BaseFetcher to add behavior to every request, including redirects.
+type RoundTripperMiddleware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an adapter to use an ordinary function as http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// ChainRoundTrippers wraps rt with mws. The first middleware is the outermost
+// one, i.e. it sees a request first and its response last.
+func ChainRoundTrippers(rt http.RoundTripper, mws ...RoundTripperMiddleware) http.RoundTripper {
+	for i := len(mws) - 1; i >= 0; i-- {
+		rt = mws[i](rt)
+	}
+	return rt
+}
+
+// RetryRoundTripper retries requests failed with a connection error or a 5xx
+// status according to policy. Retryable of the policy is not consulted as
+// there are no fetcher errors at this level yet. A request with a body is
+// retried only if its body can be rewound.
+func RetryRoundTripper(policy *RetryPolicy) RoundTripperMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			for attempt := 1; ; attempt++ {
+				resp, err := next.RoundTrip(req)
+				if err == nil && resp.StatusCode < 500 || attempt >= policy.MaxAttempts {
+					return resp, err
+				}
+				if req.Body != nil {
+					if req.GetBody == nil {
+						return resp, err
+					}
+					body, bodyErr := req.GetBody()
+					if bodyErr != nil {
+						return resp, err
+					}
+					// RoundTrippers must not modify the request, so retry a copy.
+					req = req.WithContext(req.Context())
+					req.Body = body
+				}
+				delay := policy.Delay(attempt)
+				if policy.MaxTotalDuration > 0 && time.Since(start)+delay >= policy.MaxTotalDuration {
+					return resp, err
+				}
+				if resp != nil {
+					resp.Body.Close()
+				}
+				select {
+				case <-time.After(delay):
+				case <-req.Context().Done():
+					return nil, req.Context().Err()
+				}
+			}
+		})
+	}
+}
+
+// LoggingRoundTripper logs every request with its status, the time it took
+// and Request.CorrelationID if set.
+// Nil logger means the fetch package logger.
+func LoggingRoundTripper(l *logrus.Logger) RoundTripperMiddleware {
+	if l == nil {
+		l = logger
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			begin := time.Now()
+			resp, err := next.RoundTrip(req)
+			fields := logrus.Fields{
+				"method": req.Method,
+				"took":   time.Since(begin),
+			}
+			if id := CorrelationID(req.Context()); id != "" {
+				fields["correlationID"] = id
+			}
+			if err != nil {
+				l.WithFields(fields).Errorf("Request %s: %s", req.URL, err)
+				return resp, err
+			}
+			fields["status"] = resp.StatusCode
+			l.WithFields(fields).Info("Request ", req.URL)
+			return resp, err
+		})
+	}
+}
+
+// RateLimitRoundTripper delays requests so they don't exceed the rate of limiter.
+// Requests canceled while waiting fail with the error of their context, see
+// also RateLimiter.FailFast.
+func RateLimitRoundTripper(limiter *RateLimiter) RoundTripperMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := limiter.wait(req); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// RateLimiter is a token bucket allowing a request every Interval on average
+// and bursts of up to Burst requests. It may be shared by several fetchers to
+// limit their combined rate.
+//
+// RateLimiter is safe for concurrent use.
+type RateLimiter struct {
+	// Interval is the time it takes to refill a token. Zero means no limit.
+	Interval time.Duration
+	// Burst is the bucket size. Values below 1 mean 1.
+	Burst int
+	// FailFast makes requests fail with errs.TooManyRequests right away if
+	// their context deadline expires before a token is available, instead
+	// of waiting for it.
+	FailFast bool
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewRateLimiter returns a full RateLimiter allowing a request every interval
+// and bursts of up to burst requests.
+func NewRateLimiter(interval time.Duration, burst int) *RateLimiter {
+	return &RateLimiter{Interval: interval, Burst: burst, now: time.Now}
+}
+
+// reserve takes a token and returns how long to wait until it's available.
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.now == nil {
+		l.now = time.Now
+	}
+	burst := float64(l.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	now := l.now()
+	if l.last.IsZero() {
+		l.tokens = burst
+	} else {
+		l.tokens += float64(now.Sub(l.last)) / float64(l.Interval)
+		if l.tokens > burst {
+			l.tokens = burst
+		}
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens * float64(l.Interval))
+}
+
+// unreserve returns a token taken by reserve which wasn't used.
+func (l *RateLimiter) unreserve() {
+	l.mu.Lock()
+	l.tokens++
+	l.mu.Unlock()
+}
+
+// wait blocks until req may be sent or its context is done.
+func (l *RateLimiter) wait(req *http.Request) error {
+	if l.Interval <= 0 {
+		return nil
+	}
+	delay := l.reserve()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := req.Context().Deadline(); ok && l.FailFast && time.Until(deadline) < delay {
+		l.unreserve()
+		return &errs.TooManyRequests{URL: req.URL.String()}
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		l.unreserve()
+		return req.Context().Err()
+	}
+}
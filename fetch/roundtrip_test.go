@@ -0,0 +1,197 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+//recordingRoundTripper appends name to calls before and after the wrapped round trip.
+func recordingRoundTripper(name string, calls *[]string) RoundTripperMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*calls = append(*calls, name+" request")
+			resp, err := next.RoundTrip(req)
+			*calls = append(*calls, name+" response")
+			return resp, err
+		})
+	}
+}
+
+func TestBaseFetcher_RoundTrippers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	var calls []string
+	fetcher, err := NewBaseFetcherWithOptions(WithRoundTrippers(
+		recordingRoundTripper("outer", &calls),
+		recordingRoundTripper("inner", &calls),
+	))
+	assert.NoError(t, err)
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	resp.Close()
+	assert.Equal(t, []string{"outer request", "inner request", "inner response", "outer response"}, calls)
+}
+
+func TestRetryRoundTripper(t *testing.T) {
+	ts, hits := flakyServer(2, http.StatusBadGateway)
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions(WithRoundTrippers(
+		RetryRoundTripper(&RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+	))
+	assert.NoError(t, err)
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL, FormData: "a=b"})
+	assert.NoError(t, err)
+	resp.Close()
+	assert.Equal(t, int32(3), *hits)
+
+	//the request passed to RoundTrip is not modified
+	*hits = 0
+	req, err := http.NewRequest("POST", ts.URL, strings.NewReader("a=b"))
+	assert.NoError(t, err)
+	body := req.Body
+	rt := RetryRoundTripper(&RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})(http.DefaultTransport)
+	r, err := rt.RoundTrip(req)
+	assert.NoError(t, err)
+	r.Body.Close()
+	assert.Equal(t, int32(3), *hits)
+	assert.True(t, body == req.Body)
+}
+
+func TestLoggingRoundTripper(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions(WithRoundTrippers(LoggingRoundTripper(nil)))
+	assert.NoError(t, err)
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	resp.Close()
+}
+
+func TestCorrelationID(t *testing.T) {
+	var received http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.Out = &buf
+	l.Formatter = &logrus.JSONFormatter{}
+	var labels []string
+	metrics := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			labels = append(labels, CorrelationID(req.Context()))
+			return next.RoundTrip(req)
+		})
+	}
+	fetcher, err := NewBaseFetcherWithOptions(WithRoundTrippers(LoggingRoundTripper(l), metrics))
+	assert.NoError(t, err)
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL, CorrelationID: "job-42"})
+	assert.NoError(t, err)
+	resp.Close()
+	assert.Equal(t, "job-42", received.Get("X-Request-ID"))
+	assert.Contains(t, buf.String(), `"correlationID":"job-42"`)
+	assert.Equal(t, []string{"job-42"}, labels)
+
+	fetcher.CorrelationIDHeader = "X-Correlation-ID"
+	buf.Reset()
+	resp, err = fetcher.FetchResponse(Request{URL: ts.URL, CorrelationID: "job-43"})
+	assert.NoError(t, err)
+	resp.Close()
+	assert.Equal(t, "job-43", received.Get("X-Correlation-ID"))
+	assert.Empty(t, received.Get("X-Request-ID"))
+	assert.Contains(t, buf.String(), `"correlationID":"job-43"`)
+
+	//nothing is sent or logged without correlation ID
+	buf.Reset()
+	resp, err = fetcher.FetchResponse(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	resp.Close()
+	assert.Empty(t, received.Get("X-Correlation-ID"))
+	assert.NotContains(t, buf.String(), "correlationID")
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewRateLimiter(time.Second, 2)
+	limiter.now = func() time.Time { return now }
+
+	assert.Equal(t, time.Duration(0), limiter.reserve())
+	assert.Equal(t, time.Duration(0), limiter.reserve())
+	assert.Equal(t, time.Second, limiter.reserve())
+	now = now.Add(3 * time.Second)
+	//the bucket never holds more than Burst tokens
+	assert.Equal(t, time.Duration(0), limiter.reserve())
+	assert.Equal(t, time.Duration(0), limiter.reserve())
+	assert.Equal(t, time.Second, limiter.reserve())
+}
+
+func TestRateLimitRoundTripper(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	limiter := NewRateLimiter(50*time.Millisecond, 1)
+	fetcher, err := NewBaseFetcherWithOptions(WithRoundTrippers(RateLimitRoundTripper(limiter)))
+	assert.NoError(t, err)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		resp, err := fetcher.FetchResponse(Request{URL: ts.URL})
+		assert.NoError(t, err)
+		resp.Close()
+	}
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+
+	//waiting for a token is canceled with the request
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = fetcher.FetchResponse(Request{URL: ts.URL, Context: ctx})
+	assert.Error(t, err)
+}
+
+func TestRateLimiter_FailFast(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	limiter := NewRateLimiter(time.Second, 1)
+	limiter.FailFast = true
+	fetcher, err := NewBaseFetcherWithOptions(WithRoundTrippers(RateLimitRoundTripper(limiter)))
+	assert.NoError(t, err)
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	resp.Close()
+
+	//the bucket refills after the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = fetcher.FetchResponse(Request{URL: ts.URL, Context: ctx})
+	assert.IsType(t, &errs.TooManyRequests{}, err)
+	assert.True(t, time.Since(start) < 50*time.Millisecond, "failed without waiting")
+
+	//the unused token is given back
+	limiter.mu.Lock()
+	assert.InDelta(t, 0, limiter.tokens, 0.5)
+	limiter.mu.Unlock()
+}
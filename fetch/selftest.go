@@ -0,0 +1,62 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// selfTestBytes is the number of body bytes SelfTest reads.
+const selfTestBytes = 512
+
+// SelfTest verifies the configuration of BaseFetcher before a crawl by
+// fetching probeURL through the fully configured stack: proxy, transport,
+// TLS settings, host filters, headers and signer. SessionCache is bypassed.
+// It returns nil if probeURL answers with a success status, otherwise an
+// error telling apart an unreachable proxy, failed DNS lookup, TLS failure
+// or an error status.
+func (bf *BaseFetcher) SelfTest(ctx context.Context, probeURL string) error {
+	resp, err := bf.response(Request{URL: probeURL, Context: ctx})
+	if err != nil {
+		return fmt.Errorf("self-test of %s failed: %s", probeURL, diagnose(err))
+	}
+	defer resp.Body.Close()
+	if _, err := io.CopyN(ioutil.Discard, resp.Body, selfTestBytes); err != nil && err != io.EOF {
+		return fmt.Errorf("self-test of %s failed reading the body: %s", probeURL, err)
+	}
+	return nil
+}
+
+// diagnose describes the failure of the stack err was returned by.
+func diagnose(err error) string {
+	cause := err
+	if e, ok := cause.(*errs.BadRequest); ok && e.Err != nil {
+		cause = e.Err
+	}
+	if e, ok := cause.(*url.Error); ok {
+		cause = e.Err
+	}
+	if e, ok := cause.(*net.OpError); ok && e.Op == "proxyconnect" {
+		return "proxy unreachable: " + e.Error()
+	}
+	if e, ok := cause.(*net.OpError); ok {
+		cause = e.Err
+	}
+	switch e := cause.(type) {
+	case *net.DNSError:
+		return "DNS lookup failed: " + e.Error()
+	case *errs.ProxyAuthenticationRequired:
+		return "proxy rejected the credentials: " + e.Error()
+	}
+	//certificate errors are wrapped differently depending on the Go version
+	if msg := cause.Error(); strings.Contains(msg, "x509: ") {
+		return "TLS certificate rejected: " + msg
+	}
+	return err.Error()
+}
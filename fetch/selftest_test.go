@@ -0,0 +1,54 @@
+package fetch
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_SelfTest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	//a proxy address nothing listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	brokenProxy := "http://" + l.Addr().String()
+	l.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	ctx := context.Background()
+	assert.NoError(t, fetcher.SelfTest(ctx, ts.URL))
+
+	err = fetcher.SelfTest(ctx, ts.URL+"/missing")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "404")
+	}
+	err = fetcher.SelfTest(ctx, tlsServer.URL)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "TLS certificate rejected")
+	}
+	err = fetcher.SelfTest(ctx, "http://nonexistent.invalid/")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "DNS lookup failed")
+	}
+
+	fetcher, err = NewBaseFetcherWithOptions(WithProxy(brokenProxy))
+	assert.NoError(t, err)
+	err = fetcher.SelfTest(ctx, ts.URL)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "proxy unreachable")
+	}
+}
@@ -14,6 +14,9 @@ import (
 // Config provides basic configuration
 type Config struct {
 	Host string
+	// Service handles the fetch requests, see NewFetchService. The zero
+	// value fetches with the default settings.
+	Service FetchService
 }
 
 // HTMLServer represents the web service that serves up HTML
@@ -31,7 +34,7 @@ func Start(cfg Config) *HTMLServer {
 	logger := log.NewLogger(false)
 
 	var svc Service
-	svc = FetchService{}
+	svc = cfg.Service
 
 	//svc = RobotsTxtMiddleware()(svc)
 	svc = LoggingMiddleware(logger)(svc)
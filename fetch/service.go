@@ -1,11 +1,13 @@
 package fetch
 
 import (
+	"crypto/cipher"
 	"encoding/json"
+	"fmt"
 	"io"
-	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"os"
 
 	"github.com/slotix/dataflowkit/storage"
 	"github.com/spf13/viper"
@@ -17,8 +19,49 @@ type Service interface {
 	Fetch(req Request) (io.ReadCloser, error)
 }
 
+// CookieErrorPolicy decides what happens when the cookies of a user can't
+// be loaded from or saved to storage.
+type CookieErrorPolicy int
+
+const (
+	// LogCookieErrors logs the failure and goes on with the fetch. The
+	// session of the user may be lost then. It is the default.
+	LogCookieErrors CookieErrorPolicy = iota
+	// FailOnCookieErrors fails the fetch with the storage error.
+	FailOnCookieErrors
+)
+
 // FetchService implements service with empty struct
 type FetchService struct {
+	// CookieErrors is the policy applied when the cookies of
+	// Request.UserToken can't be loaded or saved.
+	CookieErrors CookieErrorPolicy
+	// cookieCipher, if set, encrypts the cookies in storage, see
+	// WithEncryptedCookieStore.
+	cookieCipher cipher.AEAD
+}
+
+// ServiceOption sets an optional parameter of FetchService created with
+// NewFetchService.
+type ServiceOption func(*FetchService) error
+
+// NewFetchService creates FetchService configured with opts.
+func NewFetchService(opts ...ServiceOption) (FetchService, error) {
+	fs := FetchService{}
+	for _, opt := range opts {
+		if err := opt(&fs); err != nil {
+			return FetchService{}, err
+		}
+	}
+	return fs, nil
+}
+
+// WithCookieErrors sets the CookieErrors policy of FetchService.
+func WithCookieErrors(policy CookieErrorPolicy) ServiceOption {
+	return func(fs *FetchService) error {
+		fs.CookieErrors = policy
+		return nil
+	}
 }
 
 // ServiceMiddleware defines a middleware for a Fetch service
@@ -34,18 +77,17 @@ func (fs FetchService) Fetch(req Request) (io.ReadCloser, error) {
 		fetcher = newFetcher(Base)
 	}
 	var (
-		jar     http.CookieJar
-		cookies []byte
-		cArr    []*http.Cookie
-		s       storage.Store
+		jar CookieJar
+		s   storage.Store
 	)
 
 	jarOpts := &cookiejar.Options{PublicSuffixList: publicsuffix.List}
-	jar, err := cookiejar.New(jarOpts)
+	cJar, err := cookiejar.New(jarOpts)
 	if err != nil {
 		logger.Error("Failed to create Cookie Jar")
 
 	}
+	jar = NewCookieJar(cJar)
 	u, err := url.Parse(req.getURL())
 	if err != nil {
 		return nil, err
@@ -53,30 +95,9 @@ func (fs FetchService) Fetch(req Request) (io.ReadCloser, error) {
 	if req.UserToken != "" {
 		storageType := viper.GetString("STORAGE_TYPE")
 		s = storage.NewStore(storageType)
-		cookies, err = s.Read(storage.Record{
-			Type: storage.COOKIES,
-			Key:  req.UserToken,
-		})
-		if err != nil {
-			logger.Warningf("Failed to read cookie for %s. %s", req.UserToken, err.Error())
-		}
-		cArr = []*http.Cookie{}
-		if len(cookies) != 0 {
-			err = json.Unmarshal(cookies, &cArr)
-			if err != nil {
-				return nil, err
-			}
-
-			tempCarr := []*http.Cookie{}
-			for i := 0; i < len(cArr); i++ {
-				c := cArr[i]
-				if u.Host == c.Domain {
-					tempCarr = append(tempCarr, c)
-					cArr = append(cArr[:i], cArr[i+1:]...)
-					i--
-				}
-			}
-			jar.SetCookies(u, tempCarr)
+		if err = fs.loadCookies(s, jar, req.UserToken, u); err != nil {
+			s.Close()
+			return nil, err
 		}
 	}
 	fetcher.setCookieJar(jar)
@@ -85,23 +106,77 @@ func (fs FetchService) Fetch(req Request) (io.ReadCloser, error) {
 		return nil, err
 	}
 	if req.UserToken != "" {
-		jar = fetcher.getCookieJar()
-		cArr = append(cArr, jar.Cookies(u)...)
-		cookies, err = json.Marshal(cArr)
+		err = fs.saveCookies(s, fetcher.getCookieJar(), req.UserToken)
+		s.Close()
 		if err != nil {
+			res.Close()
 			return nil, err
 		}
-		err = s.Write(storage.Record{
-			Type:    storage.COOKIES,
-			Key:     req.UserToken,
-			Value:   cookies,
-			ExpTime: 0,
-		})
+	}
+	return res, nil
+}
+
+// loadCookies sets the cookies of the user identified by token stored in s
+// to jar, each for the URL it was set for, so cookies claiming a Domain the
+// URL may not set are rejected. Cookies stored without URL are set for u. A
+// user without stored cookies is not an error, other read and decryption
+// failures are handled according to CookieErrors.
+func (fs FetchService) loadCookies(s storage.Store, jar CookieJar, token string, u *url.URL) error {
+	cookies, err := s.Read(storage.Record{
+		Type: storage.COOKIES,
+		Key:  token,
+	})
+	if err != nil && !os.IsNotExist(err) {
+		if err := fs.cookieError("read", token, err); err != nil {
+			return err
+		}
+	}
+	if len(cookies) == 0 {
+		return nil
+	}
+	if fs.cookieCipher != nil {
+		if cookies, err = decryptCookies(fs.cookieCipher, cookies, token); err != nil {
+			return fs.cookieError("decrypt", token, err)
+		}
+	}
+	cArr := []StoredCookie{}
+	if err := json.Unmarshal(cookies, &cArr); err != nil {
+		return err
+	}
+	setStoredCookies(jar, cArr, u)
+	return nil
+}
 
-		if err != nil {
-			logger.Warningf("Failed to write cookie for %s. %s", req.UserToken, err.Error())
+// saveCookies writes all the cookies of jar to s as the cookies of the user
+// identified by token. Write failures are handled according to CookieErrors.
+func (fs FetchService) saveCookies(s storage.Store, jar CookieJar, token string) error {
+	cookies, err := json.Marshal(jar.AllCookies())
+	if err != nil {
+		return err
+	}
+	if fs.cookieCipher != nil {
+		if cookies, err = encryptCookies(fs.cookieCipher, cookies, token); err != nil {
+			return err
 		}
-		s.Close()
 	}
-	return res, nil
+	err = s.Write(storage.Record{
+		Type:    storage.COOKIES,
+		Key:     token,
+		Value:   cookies,
+		ExpTime: 0,
+	})
+	if err != nil {
+		return fs.cookieError("write", token, err)
+	}
+	return nil
+}
+
+// cookieError logs the failure to op the cookies of token, or returns it
+// if CookieErrors is FailOnCookieErrors.
+func (fs FetchService) cookieError(op, token string, err error) error {
+	if fs.CookieErrors == FailOnCookieErrors {
+		return fmt.Errorf("failed to %s cookies for %s: %s", op, token, err)
+	}
+	logger.Warningf("Failed to %s cookie for %s. %s", op, token, err.Error())
+	return nil
 }
@@ -4,7 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/slotix/dataflowkit/storage"
@@ -192,3 +198,128 @@ func TestFetchServiceMW(t *testing.T) {
 		t.Log(err)
 	}
 }
+
+func TestCookieErrorPolicy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "cookies")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	//a regular file in place of the storage directory makes it unwritable even for root
+	unwritable := filepath.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(unwritable, nil, 0644))
+	cJar, _ := cookiejar.New(nil)
+	jar := NewCookieJar(cJar)
+	u, _ := url.Parse(ts.URL)
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "1"}})
+
+	viper.Set("DISKV_BASE_DIR", filepath.Join(unwritable, "store"))
+	defer viper.Set("DISKV_BASE_DIR", "")
+	s := storage.NewStore("diskv")
+	assert.NoError(t, FetchService{}.saveCookies(s, jar, "token"))
+	assert.Error(t, FetchService{CookieErrors: FailOnCookieErrors}.saveCookies(s, jar, "token"))
+	failing, err := NewFetchService(WithCookieErrors(FailOnCookieErrors))
+	assert.NoError(t, err)
+	assert.Error(t, failing.saveCookies(s, jar, "token"))
+
+	//failing to load the cookies fails the fetch too
+	viper.Set("STORAGE_TYPE", "Diskv")
+	_, err = FetchService{CookieErrors: FailOnCookieErrors}.Fetch(Request{URL: ts.URL, UserToken: "token"})
+	assert.Error(t, err)
+	content, err := FetchService{}.Fetch(Request{URL: ts.URL, UserToken: "token"})
+	if assert.NoError(t, err) {
+		content.Close()
+	}
+
+	//a user without saved cookies is not an error
+	viper.Set("DISKV_BASE_DIR", filepath.Join(dir, "store"))
+	s = storage.NewStore("diskv")
+	assert.NoError(t, FetchService{CookieErrors: FailOnCookieErrors}.loadCookies(s, jar, "new user", u))
+}
+
+func TestWithEncryptedCookieStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookies")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	viper.Set("DISKV_BASE_DIR", dir)
+	defer viper.Set("DISKV_BASE_DIR", "")
+	s := storage.NewStore("diskv")
+	u, _ := url.Parse("http://example.com/")
+	cJar, _ := cookiejar.New(nil)
+	jar := NewCookieJar(cJar)
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "secret-credentials"}})
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	svc, err := NewFetchService(WithEncryptedCookieStore(key))
+	assert.NoError(t, err)
+	assert.NoError(t, svc.saveCookies(s, jar, "token"))
+
+	//nothing readable reaches the disk
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			data, _ := ioutil.ReadFile(path)
+			assert.NotContains(t, string(data), "secret-credentials", path)
+			assert.NotContains(t, string(data), "session", path)
+		}
+		return nil
+	})
+
+	cJar, _ = cookiejar.New(nil)
+	loaded := NewCookieJar(cJar)
+	assert.NoError(t, svc.loadCookies(s, loaded, "token", u))
+	if cookies := loaded.Cookies(u); assert.Len(t, cookies, 1) {
+		assert.Equal(t, "secret-credentials", cookies[0].Value)
+	}
+
+	//the cookies can't be decrypted with another key
+	other, err := NewFetchService(WithEncryptedCookieStore([]byte("fedcba9876543210")))
+	assert.NoError(t, err)
+	other.CookieErrors = FailOnCookieErrors
+	cJar, _ = cookiejar.New(nil)
+	loaded = NewCookieJar(cJar)
+	assert.Error(t, other.loadCookies(s, loaded, "token", u))
+	assert.Empty(t, loaded.AllCookies())
+
+	//nor as the cookies of another user
+	sealed, err := s.Read(storage.Record{Type: storage.COOKIES, Key: "token"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Write(storage.Record{Type: storage.COOKIES, Key: "victim", Value: sealed}))
+	svc.CookieErrors = FailOnCookieErrors
+	assert.Error(t, svc.loadCookies(s, loaded, "victim", u))
+	assert.Empty(t, loaded.AllCookies())
+
+	_, err = NewFetchService(WithEncryptedCookieStore([]byte("short")))
+	assert.Error(t, err)
+}
+
+func TestLoadCookies_Domains(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookies")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	viper.Set("DISKV_BASE_DIR", dir)
+	defer viper.Set("DISKV_BASE_DIR", "")
+	s := storage.NewStore("diskv")
+	stored, _ := json.Marshal([]StoredCookie{
+		{Cookie: http.Cookie{Name: "sso", Value: "1", Domain: "auth.example.org", Path: "/"}, URL: "http://auth.example.org/"},
+		{Cookie: http.Cookie{Name: "foreign", Value: "1", Domain: "bank.example", Path: "/"}, URL: "http://evil.example/"},
+		//stored before cookies had URLs
+		{Cookie: http.Cookie{Name: "legacy", Value: "1", Domain: "bank.example", Path: "/"}},
+		{Cookie: http.Cookie{Name: "session", Value: "1", Path: "/"}},
+	})
+	assert.NoError(t, s.Write(storage.Record{Type: storage.COOKIES, Key: "token", Value: stored}))
+
+	u, _ := url.Parse("http://evil.example/")
+	cJar, _ := cookiejar.New(nil)
+	jar := NewCookieJar(cJar)
+	assert.NoError(t, FetchService{}.loadCookies(s, jar, "token", u))
+	bank, _ := url.Parse("http://bank.example/")
+	assert.Empty(t, jar.Cookies(bank))
+	auth, _ := url.Parse("http://auth.example.org/")
+	assert.Len(t, jar.Cookies(auth), 1)
+	if cookies := jar.Cookies(u); assert.Len(t, cookies, 1) {
+		assert.Equal(t, "session", cookies[0].Name)
+	}
+}
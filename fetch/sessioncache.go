@@ -0,0 +1,340 @@
+package fetch
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// SessionCache keeps fetched documents in memory so identical requests of a
+// crawl are served without refetching, regardless of HTTP cache headers.
+// Requests are identical if their method, normalized URL, form data, body
+// compression, user token and headers are equal and so are the request
+// headers named by the Vary header of the cached response, e.g. headers set
+// by the fetcher like User-Agent. Responses with
+// "Vary: *" are not cached. Entries expire TTL after they were fetched and
+// the least recently used request is evicted once there are MaxEntries of them.
+//
+// SessionCache is safe for concurrent use.
+type SessionCache struct {
+	// MaxEntries is the maximum number of cached requests. Zero means no limit.
+	MaxEntries int
+	// TTL is how long a document is cached. Zero means until it's evicted.
+	TTL time.Duration
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+// cacheEntry holds the documents cached for a request signature, one per
+// variant of the Vary request headers.
+type cacheEntry struct {
+	key      string
+	variants []*cacheVariant
+}
+
+// cacheVariant is a fetched document kept in SessionCache.
+type cacheVariant struct {
+	resp *http.Response
+	body []byte
+	// vary are the canonical names of the request headers named by Vary
+	// and values their values in the request the document was fetched with.
+	vary    []string
+	values  []string
+	fetched time.Time
+	expires time.Time
+}
+
+// cacheStatus tells whether a response was served by SessionCache.
+type cacheStatus struct {
+	hit bool
+	// stale is set if the document expired, age is the time since it was fetched.
+	stale bool
+	age   time.Duration
+}
+
+// matches reports whether a request with header h is served by v.
+func (v *cacheVariant) matches(h http.Header) bool {
+	for i, name := range v.vary {
+		if v.values[i] != strings.Join(h[name], ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// NewSessionCache returns SessionCache keeping up to maxEntries requests for ttl.
+func NewSessionCache(maxEntries int, ttl time.Duration) *SessionCache {
+	return &SessionCache{
+		MaxEntries: maxEntries,
+		TTL:        ttl,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+// Len returns the number of cached requests.
+func (c *SessionCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// get returns a copy of the response cached under key for request header h
+// with a fresh body. Expired documents are dropped unless keepStale is set,
+// then they are returned too and the status reports whether the document expired.
+func (c *SessionCache) get(key string, h http.Header, keepStale bool) (resp *http.Response, status cacheStatus, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, status, false
+	}
+	e := el.Value.(*cacheEntry)
+	now := c.now()
+	live := e.variants[:0]
+	var found *cacheVariant
+	for _, v := range e.variants {
+		expired := !v.expires.IsZero() && !now.Before(v.expires)
+		if expired && !keepStale {
+			continue
+		}
+		live = append(live, v)
+		if found == nil && v.matches(h) {
+			found = v
+			status = cacheStatus{hit: true, stale: expired, age: now.Sub(v.fetched)}
+		}
+	}
+	e.variants = live
+	if len(live) == 0 {
+		c.remove(el)
+		return nil, cacheStatus{}, false
+	}
+	if found == nil {
+		return nil, cacheStatus{}, false
+	}
+	c.ll.MoveToFront(el)
+	cached := *found.resp
+	cached.Body = ioutil.NopCloser(bytes.NewReader(found.body))
+	return &cached, status, true
+}
+
+// add caches resp fetched with request header h and its body read into
+// body under key.
+func (c *SessionCache) add(key string, h http.Header, resp *http.Response, body []byte) {
+	var vary []string
+	for _, value := range resp.Header["Vary"] {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return
+			}
+			if name != "" {
+				vary = append(vary, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	if resp.Request != nil {
+		//don't keep the context of the request, e.g. its byte counter
+		stored := *resp
+		stored.Request = resp.Request.WithContext(context.Background())
+		resp = &stored
+	}
+	v := &cacheVariant{resp: resp, body: body, vary: vary, values: make([]string, len(vary))}
+	for i, name := range vary {
+		v.values[i] = strings.Join(h[name], ",")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ll == nil {
+		c.ll = list.New()
+		c.items = make(map[string]*list.Element)
+	}
+	if c.now == nil {
+		c.now = time.Now
+	}
+	v.fetched = c.now()
+	if c.TTL > 0 {
+		v.expires = v.fetched.Add(c.TTL)
+	}
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*cacheEntry)
+		variants := []*cacheVariant{v}
+		for _, old := range e.variants {
+			if !sameVariant(old, v) {
+				variants = append(variants, old)
+			}
+		}
+		e.variants = variants
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, variants: []*cacheVariant{v}})
+	if c.MaxEntries > 0 && c.ll.Len() > c.MaxEntries {
+		c.remove(c.ll.Back())
+	}
+}
+
+// sameVariant reports whether a and b are fetched with the same Vary request headers.
+func sameVariant(a, b *cacheVariant) bool {
+	if strings.Join(a.vary, "\n") != strings.Join(b.vary, "\n") {
+		return false
+	}
+	return strings.Join(a.values, "\n") == strings.Join(b.values, "\n")
+}
+
+// remove drops el from the cache. c.mu must be held.
+func (c *SessionCache) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*cacheEntry).key)
+}
+
+// cached returns the response to r from bf.SessionCache or fetches it with
+// fetch and caches it. The body of a fetched response is read into memory.
+// If bf.OfflineFallback is set and fetch fails to connect, an expired
+// document is returned instead with a stale status.
+func (bf *BaseFetcher) cached(r Request, fetch func(Request) (*http.Response, error)) (*http.Response, cacheStatus, error) {
+	key := requestSignature(r)
+	h := http.Header{}
+	bf.setHeaders(h, r)
+	cachedResp, status, ok := bf.SessionCache.get(key, h, bf.OfflineFallback)
+	if ok && !status.stale {
+		return cachedResp, status, nil
+	}
+	resp, err := fetch(r)
+	if err != nil {
+		if ok && isOffline(err) {
+			logger.Warningf("Serving stale copy of %s: %s", r.URL, err)
+			return cachedResp, status, nil
+		}
+		return nil, cacheStatus{}, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, cacheStatus{}, err
+	}
+	resp.Body = nil
+	bf.SessionCache.add(key, h, resp, body)
+	fetched := *resp
+	fetched.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return &fetched, cacheStatus{}, nil
+}
+
+// isOffline reports whether a request failed with err because the host
+// couldn't be reached, rather than with an error response or e.g. an
+// invalid certificate, see IsConnectionError.
+func isOffline(err error) bool {
+	e, ok := err.(*errs.BadRequest)
+	return ok && e.Err != nil && IsConnectionError(e.Err)
+}
+
+// Warm fetches requests into SessionCache without returning them, e.g. to
+// prime the cache before a crawl. Up to concurrency requests are fetched at
+// once, values below 1 mean 1. Host limits, SlowStart and rate limiting
+// round trippers apply as usual. A failed request doesn't stop the others,
+// the errors of all failed requests are returned.
+func (bf *BaseFetcher) Warm(requests []Request, concurrency int) []error {
+	if bf.SessionCache == nil {
+		return []error{errors.New("fetcher has no SessionCache to warm")}
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []error
+	)
+	sem := make(chan struct{}, concurrency)
+	for _, r := range requests {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(r Request) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			resp, err := bf.FetchResponse(r)
+			if err == nil {
+				//the body is already in memory
+				err = resp.Close()
+			}
+			if err != nil {
+				mu.Lock()
+				failed = append(failed, err)
+				mu.Unlock()
+			}
+		}(r)
+	}
+	wg.Wait()
+	return failed
+}
+
+// requestSignature returns the key identical requests are cached under.
+func requestSignature(r Request) string {
+	gzipBody := ""
+	if r.GzipBody {
+		gzipBody = "gzip"
+	}
+	return strings.Join([]string{requestMethod(r), normalizeURL(r.getURL()), r.FormData, gzipBody, r.UserToken, strings.ToLower(r.HostOverride), r.ProxyURL, headerSignature(r.Headers)}, "\n")
+}
+
+// headerSignature returns h with canonical names and sorted names and values,
+// so requests with the same headers in a different order are identical.
+func headerSignature(h http.Header) string {
+	lines := make([]string, 0, len(h))
+	for name, values := range h {
+		sorted := append([]string(nil), values...)
+		sort.Strings(sorted)
+		lines = append(lines, http.CanonicalHeaderKey(name)+": "+strings.Join(sorted, ","))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\r\n")
+}
+
+// requestMethod returns the HTTP method r is sent with.
+func requestMethod(r Request) string {
+	if r.FormData != "" {
+		return "POST"
+	}
+	if r.Method == "" {
+		return "GET"
+	}
+	return strings.ToUpper(r.Method)
+}
+
+// normalizeURL returns rawurl with lower case scheme and host, without
+// default port and fragment and with sorted query parameters.
+// Unparsable URLs are returned unchanged.
+func normalizeURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	u.RawQuery = u.Query().Encode()
+	return u.String()
+}
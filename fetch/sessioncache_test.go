@@ -0,0 +1,280 @@
+package fetch
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_SessionCache(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer ts.Close()
+
+	now := time.Now()
+	cache := NewSessionCache(2, time.Minute)
+	cache.now = func() time.Time { return now }
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.SessionCache = cache
+
+	get := func(url string) string {
+		resp, err := fetcher.FetchResponse(Request{URL: url})
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		defer resp.Close()
+		assert.Equal(t, 200, resp.GetStatusCode())
+		body, err := ioutil.ReadAll(resp)
+		assert.NoError(t, err)
+		return string(body)
+	}
+
+	// miss, then hit
+	assert.Equal(t, "/a", get(ts.URL+"/a"))
+	assert.Equal(t, "/a", get(ts.URL+"/a#fragment"))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&hits))
+
+	// a different URL misses
+	assert.Equal(t, "/b", get(ts.URL+"/b"))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+	assert.Equal(t, 2, cache.Len())
+
+	// /b is evicted as least recently used
+	get(ts.URL + "/a")
+	get(ts.URL + "/c")
+	assert.EqualValues(t, 3, atomic.LoadInt32(&hits))
+	get(ts.URL + "/a")
+	assert.EqualValues(t, 3, atomic.LoadInt32(&hits))
+	get(ts.URL + "/b")
+	assert.EqualValues(t, 4, atomic.LoadInt32(&hits))
+
+	// entries expire after TTL
+	now = now.Add(time.Minute)
+	get(ts.URL + "/b")
+	assert.EqualValues(t, 5, atomic.LoadInt32(&hits))
+}
+
+func TestRequestSignature(t *testing.T) {
+	same := []Request{
+		{URL: "http://Example.com/?b=2&a=1"},
+		{URL: "http://example.com:80?a=1&b=2", Method: "GET"},
+		{URL: "http://example.com/?a=1&b=2#top", Method: "get"},
+	}
+	headers := []Request{
+		{URL: "http://example.com/", Headers: http.Header{"Accept": {"text/html", "*/*"}, "X-A": {"1"}}},
+		{URL: "http://example.com/", Headers: http.Header{"X-A": {"1"}, "accept": {"*/*", "text/html"}}},
+	}
+	assert.Equal(t, requestSignature(headers[0]), requestSignature(headers[1]))
+	for _, r := range same[1:] {
+		assert.Equal(t, requestSignature(same[0]), requestSignature(r), r.URL)
+	}
+	different := []Request{
+		{URL: "https://example.com/?a=1&b=2"},
+		{URL: "http://example.com/?a=1&b=2", Method: "HEAD"},
+		{URL: "http://example.com/?a=1&b=2", FormData: "user=a"},
+		{URL: "http://example.com/?a=1&b=2", UserToken: "token"},
+		{URL: "http://example.com/?a=1&b=2", HostOverride: "www.example.com"},
+		{URL: "http://example.com/?a=1&b=2", ProxyURL: "http://de.proxy:3128"},
+		{URL: "http://example.com/?a=1&b=2", Headers: http.Header{"Authorization": {"Bearer a"}}},
+		{URL: "http://example.com/?a=1&b=2", FormData: "user=a", GzipBody: true},
+	}
+	for _, r := range different {
+		assert.NotEqual(t, requestSignature(same[0]), requestSignature(r), r)
+	}
+}
+
+func TestSessionCache_Vary(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/any" {
+			w.Header().Set("Vary", "*")
+		} else {
+			w.Header().Set("Vary", "accept-encoding, User-Agent")
+		}
+		w.Write([]byte(r.Header.Get("Accept-Encoding") + "|" + r.Header.Get("User-Agent")))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions(WithUserAgent("dfk"))
+	assert.NoError(t, err)
+	fetcher.SessionCache = NewSessionCache(10, time.Minute)
+	get := func(url string, header http.Header) string {
+		content, err := fetcher.Fetch(Request{URL: url, Headers: header})
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		defer content.Close()
+		data, _ := ioutil.ReadAll(content)
+		return string(data)
+	}
+	identity := http.Header{"Accept-Encoding": {"identity"}}
+
+	assert.Equal(t, "identity|dfk", get(ts.URL, identity))
+	assert.Equal(t, "identity|dfk", get(ts.URL, identity))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&hits))
+
+	//a variant of headers set by the fetcher is fetched and cached alongside
+	fetcher.UserAgent = "other"
+	assert.Equal(t, "identity|other", get(ts.URL, identity))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+	fetcher.UserAgent = "dfk"
+	assert.Equal(t, "identity|dfk", get(ts.URL, identity))
+	fetcher.UserAgent = "other"
+	assert.Equal(t, "identity|other", get(ts.URL, identity))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+	assert.Equal(t, 1, fetcher.SessionCache.Len())
+	fetcher.UserAgent = "dfk"
+
+	//request headers are part of the key
+	assert.Equal(t, "gzip|dfk", get(ts.URL, http.Header{"Accept-Encoding": {"gzip"}}))
+	assert.Equal(t, "gzip|dfk", get(ts.URL, http.Header{"Accept-Encoding": {"gzip"}}))
+	assert.EqualValues(t, 3, atomic.LoadInt32(&hits))
+	assert.Equal(t, 2, fetcher.SessionCache.Len())
+
+	//Vary: * is never cached
+	get(ts.URL+"/any", nil)
+	get(ts.URL+"/any", nil)
+	assert.EqualValues(t, 5, atomic.LoadInt32(&hits))
+}
+
+func TestBaseFetcher_OfflineFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("cached"))
+	}))
+
+	now := time.Now()
+	cache := NewSessionCache(10, time.Minute)
+	cache.now = func() time.Time { return now }
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.SessionCache = cache
+	fetcher.OfflineFallback = true
+
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	assert.False(t, resp.Stale())
+	resp.Close()
+
+	//the network is down once the cached copy expired
+	ts.Close()
+	now = now.Add(time.Hour)
+	resp, err = fetcher.FetchResponse(Request{URL: ts.URL})
+	if assert.NoError(t, err) {
+		assert.True(t, resp.Stale())
+		body, _ := ioutil.ReadAll(resp)
+		assert.Equal(t, "cached", string(body))
+		resp.Close()
+	}
+
+	//there is no cached copy to fall back to
+	_, err = fetcher.FetchResponse(Request{URL: ts.URL + "/other"})
+	assert.Error(t, err)
+
+	//expired copies are dropped without OfflineFallback
+	fetcher.OfflineFallback = false
+	_, err = fetcher.FetchResponse(Request{URL: ts.URL})
+	assert.Error(t, err)
+}
+
+func TestBaseFetcher_OfflineFallbackCertificate(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("cached"))
+	}))
+	defer ts.Close()
+
+	now := time.Now()
+	cache := NewSessionCache(10, time.Minute)
+	cache.now = func() time.Time { return now }
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.SessionCache = cache
+	fetcher.OfflineFallback = true
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	fetcher.transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	resp, err := fetcher.FetchResponse(Request{URL: ts.URL})
+	if assert.NoError(t, err) {
+		resp.Close()
+	}
+
+	//the host is reachable but its certificate is not trusted any longer
+	fetcher.transport.CloseIdleConnections()
+	fetcher.transport.TLSClientConfig = &tls.Config{}
+	now = now.Add(time.Hour)
+	_, err = fetcher.FetchResponse(Request{URL: ts.URL})
+	assert.Error(t, err)
+}
+
+func TestBaseFetcher_Warm(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	assert.Len(t, fetcher.Warm([]Request{{URL: ts.URL}}, 2), 1)
+
+	fetcher.SessionCache = NewSessionCache(10, time.Minute)
+	var requests []Request
+	for _, path := range []string{"/a", "/b", "/c", "/missing"} {
+		requests = append(requests, Request{URL: ts.URL + path})
+	}
+	failed := fetcher.Warm(requests, 2)
+	if assert.Len(t, failed, 1) {
+		assert.IsType(t, &errs.NotFound{}, failed[0])
+	}
+	assert.Equal(t, 3, fetcher.SessionCache.Len())
+
+	//warmed requests are served from the cache
+	content, err := fetcher.Fetch(Request{URL: ts.URL + "/b"})
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(content)
+	content.Close()
+	assert.Equal(t, "/b", string(body))
+	assert.EqualValues(t, 4, atomic.LoadInt32(&hits))
+}
+
+func TestSessionCache_Credentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//no Vary header
+		w.Write([]byte("private data of " + r.Header.Get("Authorization")))
+	}))
+	defer ts.Close()
+
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.SessionCache = NewSessionCache(10, time.Minute)
+	get := func(authorization string) string {
+		content, err := fetcher.Fetch(Request{URL: ts.URL, Headers: http.Header{"Authorization": {authorization}}})
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		defer content.Close()
+		data, _ := ioutil.ReadAll(content)
+		return string(data)
+	}
+	assert.Equal(t, "private data of Basic alice", get("Basic alice"))
+	assert.Equal(t, "private data of Basic bob", get("Basic bob"))
+	assert.Equal(t, "private data of Basic alice", get("Basic alice"))
+	assert.Equal(t, 2, fetcher.SessionCache.Len())
+}
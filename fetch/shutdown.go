@@ -0,0 +1,110 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// inflight tracks the fetches in progress so a fetcher can be closed
+// gracefully. The zero value is ready to use.
+type inflight struct {
+	mu      sync.Mutex
+	closed  bool
+	wg      sync.WaitGroup
+	next    int
+	cancels map[int]context.CancelFunc
+}
+
+// start registers a fetch of rawurl made with the parent context, nil means
+// context.Background. It returns the context the fetch is to be made with
+// and done to be called once the fetch is over. Fetches fail with
+// errs.Canceled after close.
+func (f *inflight) start(parent context.Context, rawurl string) (context.Context, func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, nil, &errs.Canceled{URL: rawurl}
+	}
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	if f.cancels == nil {
+		f.cancels = make(map[int]context.CancelFunc)
+	}
+	id := f.next
+	f.next++
+	f.cancels[id] = cancel
+	f.wg.Add(1)
+	var once sync.Once
+	done := func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.cancels, id)
+			f.mu.Unlock()
+			cancel()
+			f.wg.Done()
+		})
+	}
+	return ctx, done, nil
+}
+
+// close rejects new fetches and waits up to timeout for the fetches in
+// progress to finish. Fetches still in progress then are canceled.
+func (f *inflight) close(timeout time.Duration) error {
+	f.mu.Lock()
+	f.closed = true
+	f.mu.Unlock()
+	finished := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(finished)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-finished:
+		return nil
+	case <-timer.C:
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, cancel := range f.cancels {
+		cancel()
+	}
+	if len(f.cancels) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d fetches canceled on close", len(f.cancels))
+}
+
+// Close shuts BaseFetcher down. New fetches fail with errs.Canceled, fetches
+// in progress, including reading of returned bodies, get ShutdownTimeout to
+// finish before they are canceled and idle connections are closed. An error
+// is returned if fetches had to be canceled. Connections of a client passed
+// to NewBaseFetcherFromClient are left to their transport, which may be
+// shared with other clients.
+func (bf *BaseFetcher) Close() error {
+	err := bf.inflight.close(bf.ShutdownTimeout)
+	if bf.transport != nil {
+		bf.transport.CloseIdleConnections()
+		bf.proxyTransports.closeIdleConnections()
+	}
+	return err
+}
+
+// Close shuts ChromeFetcher down like BaseFetcher.Close. Pages being
+// rendered get ShutdownTimeout to finish. http.DefaultTransport, used
+// without proxy, is shared and keeps its connections.
+func (f *ChromeFetcher) Close() error {
+	err := f.inflight.close(f.ShutdownTimeout)
+	if t, ok := f.client.Transport.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+	return err
+}
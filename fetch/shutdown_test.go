@@ -0,0 +1,87 @@
+package fetch
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_Close(t *testing.T) {
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			w.(http.Flusher).Flush()
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}
+		w.Write(helloContent)
+	}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			atomic.AddInt32(&conns, 1)
+		case http.StateClosed:
+			atomic.AddInt32(&conns, -1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	//a fetch in progress finishes within the timeout
+	fetcher, err := NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.ShutdownTimeout = time.Second
+	content, err := fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	go func(content io.ReadCloser) {
+		time.Sleep(50 * time.Millisecond)
+		ioutil.ReadAll(content)
+		content.Close()
+	}(content)
+	assert.NoError(t, fetcher.Close())
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.IsType(t, &errs.Canceled{}, err)
+	//idle connections are closed
+	for i := 0; i < 100 && atomic.LoadInt32(&conns) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&conns))
+
+	//a fetch still in progress after the timeout is canceled
+	fetcher, err = NewBaseFetcherWithOptions()
+	assert.NoError(t, err)
+	fetcher.ShutdownTimeout = 50 * time.Millisecond
+	content, err = fetcher.Fetch(Request{URL: ts.URL + "/slow"})
+	assert.NoError(t, err)
+	start := time.Now()
+	assert.Error(t, fetcher.Close())
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	_, err = ioutil.ReadAll(content)
+	assert.Error(t, err)
+	content.Close()
+
+	//connections of a shared transport are kept
+	for i := 0; i < 100 && atomic.LoadInt32(&conns) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	shared := &http.Transport{}
+	defer shared.CloseIdleConnections()
+	fetcher = NewBaseFetcherFromClient(&http.Client{Transport: shared})
+	content, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	ioutil.ReadAll(content)
+	content.Close()
+	assert.NoError(t, fetcher.Close())
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conns))
+}
@@ -0,0 +1,19 @@
+package fetch
+
+import "net/http"
+
+// RequestSigner signs requests of APIs requiring authenticated requests,
+// e.g. AWS Signature Version 4 or OAuth 1.0a, typically by adding an
+// Authorization header. Sign is called once the request is built with all
+// its headers, right before it is sent. Redirects are not signed.
+type RequestSigner interface {
+	Sign(req *http.Request) error
+}
+
+// RequestSignerFunc is an adapter to use an ordinary function as RequestSigner.
+type RequestSignerFunc func(req *http.Request) error
+
+// Sign calls f(req).
+func (f RequestSignerFunc) Sign(req *http.Request) error {
+	return f(req)
+}
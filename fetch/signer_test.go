@@ -0,0 +1,42 @@
+package fetch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_RequestSigner(t *testing.T) {
+	var authorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer ts.Close()
+
+	var signedUserAgent string
+	fetcher, err := NewBaseFetcherWithOptions(
+		WithUserAgent("dfk"),
+		WithRequestSigner(RequestSignerFunc(func(req *http.Request) error {
+			signedUserAgent = req.Header.Get("User-Agent")
+			req.Header.Set("Authorization", "Signature "+req.Method+" "+req.URL.Path)
+			return nil
+		})),
+	)
+	assert.NoError(t, err)
+	content, err := fetcher.Fetch(Request{URL: ts.URL + "/api"})
+	assert.NoError(t, err)
+	content.Close()
+	assert.Equal(t, "Signature GET /api", authorization)
+	//the request is signed with its final headers
+	assert.Equal(t, "dfk", signedUserAgent)
+
+	fetcher.Signer = RequestSignerFunc(func(req *http.Request) error {
+		return errors.New("missing credentials")
+	})
+	_, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.IsType(t, &errs.BadRequest{}, err)
+}
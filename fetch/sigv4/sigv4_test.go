@@ -0,0 +1,52 @@
+package sigv4
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//testSigner uses the credentials and time of the AWS Signature Version 4 test suite.
+func testSigner() *Signer {
+	s := NewSigner("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "service")
+	s.now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+	return s
+}
+
+func TestSigner_Sign(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", tt.url, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, testSigner().Sign(req), tt.name)
+		assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"), tt.name)
+		assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+			"SignedHeaders=host;x-amz-date, Signature="+tt.signature, req.Header.Get("Authorization"), tt.name)
+	}
+}
+
+func TestSigner_SignS3(t *testing.T) {
+	s := testSigner()
+	s.Service = "s3"
+	s.SessionToken = "token"
+	req, err := http.NewRequest("PUT", "https://bucket.s3.amazonaws.com/key", strings.NewReader("content"))
+	assert.NoError(t, err)
+	assert.NoError(t, s.Sign(req))
+	assert.Equal(t, "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73", req.Header.Get("X-Amz-Content-Sha256"))
+	assert.Equal(t, "token", req.Header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,")
+	//the body is still there to be sent
+	body := make([]byte, 7)
+	n, _ := req.Body.Read(body)
+	assert.Equal(t, "content", string(body[:n]))
+}
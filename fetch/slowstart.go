@@ -0,0 +1,190 @@
+package fetch
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+)
+
+// SlowStart limits the number of simultaneous requests BaseFetcher sends to
+// a single host. A host starts with InitialConcurrency slots and a delay of
+// InitialDelay before every request. Each RampAfter consecutive successful
+// responses multiply the number of slots by RampFactor, up to MaxConcurrency,
+// and shorten the delay accordingly. An erroneous response divides the number
+// of slots by RampFactor again, never dropping below InitialConcurrency.
+//
+// A slot is taken until the response body is read or closed. Fields left
+// zero or invalid take the defaults of NewSlowStart.
+//
+// SlowStart is safe for concurrent use and is meant to be shared by all
+// requests of a crawl.
+type SlowStart struct {
+	InitialConcurrency int
+	MaxConcurrency     int
+	RampFactor         float64
+	// RampAfter is the number of consecutive successes needed to ramp up.
+	RampAfter int
+	// InitialDelay is the pause before each request while a host is at
+	// InitialConcurrency. It is scaled down proportionally as concurrency grows.
+	InitialDelay time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostRamp
+}
+
+// hostRamp is the state SlowStart keeps per host.
+type hostRamp struct {
+	limit     int
+	inFlight  int
+	successes int
+	// freed is closed and replaced whenever a slot is freed or the limit changes.
+	freed chan struct{}
+}
+
+// slowStartConfig are the settings of SlowStart in effect.
+type slowStartConfig struct {
+	initial, max int
+	rampFactor   float64
+	rampAfter    int
+}
+
+// NewSlowStart returns SlowStart ramping from initial to max simultaneous
+// requests per host by rampFactor.
+func NewSlowStart(initial, max int, rampFactor float64) *SlowStart {
+	if initial < 1 {
+		initial = 1
+	}
+	if max < initial {
+		max = initial
+	}
+	if rampFactor <= 1 {
+		rampFactor = 2
+	}
+	return &SlowStart{
+		InitialConcurrency: initial,
+		MaxConcurrency:     max,
+		RampFactor:         rampFactor,
+		RampAfter:          5,
+		InitialDelay:       time.Second,
+		hosts:              make(map[string]*hostRamp),
+	}
+}
+
+// Concurrency returns the current number of simultaneous requests allowed to host.
+func (s *SlowStart) Concurrency(host string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.host(host).limit
+}
+
+// config returns the settings of s, replacing invalid ones with the
+// defaults of NewSlowStart. s.mu must be held.
+func (s *SlowStart) config() slowStartConfig {
+	c := slowStartConfig{
+		initial:    s.InitialConcurrency,
+		max:        s.MaxConcurrency,
+		rampFactor: s.RampFactor,
+		rampAfter:  s.RampAfter,
+	}
+	if c.initial < 1 {
+		c.initial = 1
+	}
+	if c.max < c.initial {
+		c.max = c.initial
+	}
+	if c.rampFactor <= 1 {
+		c.rampFactor = 2
+	}
+	if c.rampAfter < 1 {
+		c.rampAfter = 5
+	}
+	return c
+}
+
+// host returns the state of host. s.mu must be held.
+func (s *SlowStart) host(host string) *hostRamp {
+	if s.hosts == nil {
+		s.hosts = make(map[string]*hostRamp)
+	}
+	h, ok := s.hosts[host]
+	if !ok {
+		h = &hostRamp{limit: s.config().initial, freed: make(chan struct{})}
+		s.hosts[host] = h
+	}
+	return h
+}
+
+// acquire blocks until a slot for host is free and then waits for the delay
+// matching the current concurrency of host. It gives up with the error of
+// ctx once ctx is done.
+func (s *SlowStart) acquire(ctx context.Context, host string) error {
+	s.mu.Lock()
+	h := s.host(host)
+	for h.inFlight >= h.limit {
+		freed := h.freed
+		s.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		s.mu.Lock()
+	}
+	h.inFlight++
+	delay := s.InitialDelay * time.Duration(s.config().initial) / time.Duration(h.limit)
+	s.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		h.inFlight--
+		h.wake()
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// release frees the slot taken by acquire and adjusts the concurrency of host
+// according to the outcome of the request.
+func (s *SlowStart) release(host string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.config()
+	h := s.host(host)
+	h.inFlight--
+	switch err.(type) {
+	case nil:
+		h.successes++
+		if h.successes >= c.rampAfter {
+			h.successes = 0
+			h.limit = int(math.Ceil(float64(h.limit) * c.rampFactor))
+			if h.limit > c.max {
+				h.limit = c.max
+			}
+		}
+	case *errs.NotFound:
+		//missing page says nothing about host health
+	default:
+		h.successes = 0
+		h.limit = int(float64(h.limit) / c.rampFactor)
+		if h.limit < c.initial {
+			h.limit = c.initial
+		}
+	}
+	h.wake()
+}
+
+// wake wakes up the requests waiting for a slot of h. SlowStart.mu must be held.
+func (h *hostRamp) wake() {
+	close(h.freed)
+	h.freed = make(chan struct{})
+}
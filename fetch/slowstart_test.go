@@ -0,0 +1,114 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/slotix/dataflowkit/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFetcher_SlowStart(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status/500":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/status/404":
+			http.NotFound(w, r)
+		default:
+			w.Write(helloContent)
+		}
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+
+	fetcher := newBaseFetcher()
+	fetcher.SlowStart = NewSlowStart(1, 4, 2)
+	fetcher.SlowStart.RampAfter = 2
+	fetcher.SlowStart.InitialDelay = 0
+	get := func(path string) error {
+		content, err := fetcher.Fetch(Request{URL: ts.URL + path})
+		if err == nil {
+			content.Close()
+		}
+		return err
+	}
+
+	assert.Equal(t, 1, fetcher.SlowStart.Concurrency(u.Host))
+	for _, expected := range []int{1, 2, 2, 4, 4, 4} {
+		assert.NoError(t, get("/hello"))
+		assert.Equal(t, expected, fetcher.SlowStart.Concurrency(u.Host))
+	}
+	//an error tightens concurrency again
+	assert.Error(t, get("/status/500"))
+	assert.Equal(t, 2, fetcher.SlowStart.Concurrency(u.Host))
+	//missing pages don't affect it
+	assert.Error(t, get("/status/404"))
+	assert.Equal(t, 2, fetcher.SlowStart.Concurrency(u.Host))
+}
+
+func TestSlowStart_Defaults(t *testing.T) {
+	//fields left zero take the defaults of NewSlowStart
+	s := &SlowStart{}
+	ctx := context.Background()
+	assert.NoError(t, s.acquire(ctx, "example.com"))
+	s.release("example.com", nil)
+	assert.NoError(t, s.acquire(ctx, "example.com"))
+	s.release("example.com", nil)
+	assert.Equal(t, 1, s.Concurrency("example.com"))
+
+	//invalid ramp factors don't stop ramping up
+	s = &SlowStart{InitialConcurrency: 1, MaxConcurrency: 8, RampFactor: 1, RampAfter: 1}
+	assert.NoError(t, s.acquire(ctx, "example.com"))
+	s.release("example.com", nil)
+	assert.Equal(t, 2, s.Concurrency("example.com"))
+	assert.NoError(t, s.acquire(ctx, "example.com"))
+	s.release("example.com", errors.New("failed"))
+	assert.Equal(t, 1, s.Concurrency("example.com"))
+}
+
+func TestSlowStart_Context(t *testing.T) {
+	s := NewSlowStart(1, 1, 2)
+	s.InitialDelay = 0
+	assert.NoError(t, s.acquire(context.Background(), "example.com"))
+	//waiting for a slot
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, s.acquire(ctx, "example.com"))
+	s.release("example.com", nil)
+
+	//waiting for the delay, the slot is given back
+	s.InitialDelay = time.Hour
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, s.acquire(ctx, "example.com"))
+	s.InitialDelay = 0
+	assert.NoError(t, s.acquire(context.Background(), "example.com"))
+}
+
+func TestBaseFetcher_SlowStartBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(helloContent)
+	}))
+	defer ts.Close()
+
+	fetcher := newBaseFetcher()
+	fetcher.SlowStart = NewSlowStart(1, 1, 2)
+	fetcher.SlowStart.InitialDelay = 0
+	content, err := fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	//the slot is taken until the body is closed
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = fetcher.Fetch(Request{URL: ts.URL, Context: ctx})
+	assert.IsType(t, &errs.GatewayTimeout{}, err)
+	content.Close()
+	content, err = fetcher.Fetch(Request{URL: ts.URL})
+	assert.NoError(t, err)
+	content.Close()
+}
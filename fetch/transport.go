@@ -2,6 +2,7 @@ package fetch
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -38,7 +39,7 @@ func newHttpHandler(ctx context.Context, endpoint endpoints, logger *logrus.Logg
 func decodeRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	var request Request
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		return nil, &errs.BadRequest{err}
+		return nil, &errs.BadRequest{Err: err}
 	}
 	return request, nil
 }
@@ -61,6 +62,10 @@ func encodeFetcherContent(ctx context.Context, w http.ResponseWriter, response i
 }
 
 // encodeError encodes erroneous responses and writes http status header.
+// statusClientClosedRequest is the non-standard status of requests canceled
+// by the client, as logged by nginx.
+const statusClientClosedRequest = 499
+
 func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 	if err == nil {
 		panic("encodeError with nil error")
@@ -80,7 +85,10 @@ func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 		//return 401 Status
 		httpStatus = http.StatusUnauthorized
 	case *errs.ForbiddenByRobots,
-		*errs.Forbidden:
+		*errs.Forbidden,
+		*errs.ForbiddenHost,
+		*errs.Blocked,
+		*errs.ForbiddenRedirect:
 		//return 403 Status
 		httpStatus = http.StatusForbidden
 	case *errs.ProxyAuthenticationRequired:
@@ -89,10 +97,26 @@ func encodeError(_ context.Context, err error, w http.ResponseWriter) {
 	case *errs.NotFound:
 		//return 404 Status
 		httpStatus = http.StatusNotFound
-	case *errs.BadGateway:
+	case *errs.TooManyRequests:
+		//return 429 Status
+		httpStatus = http.StatusTooManyRequests
+	case *errs.BadGateway,
+		*errs.BadRedirect,
+		*errs.EmptyResponse,
+		*errs.IncompleteRead,
+		*errs.InvalidJSON,
+		*errs.UnsupportedContentType,
+		*errs.BodyTooLarge:
 		//return 502 Status
 		httpStatus = http.StatusBadGateway
-	case *errs.GatewayTimeout:
+	case *errs.Canceled:
+		//return 499 Status
+		httpStatus = statusClientClosedRequest
+	case *errs.ServiceUnavailable:
+		//return 503 Status
+		httpStatus = http.StatusServiceUnavailable
+	case *errs.GatewayTimeout,
+		*errs.ConnectionTimeout:
 		//return 504 Status
 		httpStatus = http.StatusGatewayTimeout
 	}
@@ -108,10 +132,15 @@ type endpoints struct {
 	fetchEndpoint endpoint.Endpoint
 }
 
-// MakeFetchEndpoint creates Fetch Endpoint
+// MakeFetchEndpoint creates Fetch Endpoint. A request of another type than
+// Request returns errs.BadRequest instead of panicking.
 func makeFetchEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		return svc.Fetch(request.(Request))
+		req, ok := request.(Request)
+		if !ok {
+			return nil, &errs.BadRequest{Err: fmt.Errorf("expected fetch.Request, got %T", request)}
+		}
+		return svc.Fetch(req)
 	}
 }
 
@@ -1,12 +1,14 @@
 package fetch
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gorilla/mux"
+	"github.com/slotix/dataflowkit/errs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,3 +44,27 @@ func TestQuery(t *testing.T) {
 		t.Errorf("query did not hit")
 	}
 }
+
+func TestFetchEndpoint_WrongRequestType(t *testing.T) {
+	fetchEndpoint := makeFetchEndpoint(FetchService{})
+	for _, request := range []interface{}{nil, "http://example.com", &Request{}} {
+		_, err := fetchEndpoint(context.Background(), request)
+		if assert.IsType(t, &errs.BadRequest{}, err) {
+			assert.Contains(t, err.Error(), "expected fetch.Request")
+		}
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	statuses := map[error]int{
+		&errs.Canceled{URL: "http://example.com"}: statusClientClosedRequest,
+		&errs.GatewayTimeout{}:                    http.StatusGatewayTimeout,
+		&errs.NotFound{URL: "http://example.com"}: http.StatusNotFound,
+		&errs.BadRequest{Err: context.Canceled}:   http.StatusBadRequest,
+	}
+	for err, status := range statuses {
+		w := httptest.NewRecorder()
+		encodeError(context.Background(), err, w)
+		assert.Equal(t, status, w.Code, err.Error())
+	}
+}
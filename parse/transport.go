@@ -22,7 +22,7 @@ import (
 func DecodeParseRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	var p scrape.Payload
 	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-		return nil, &errs.BadRequest{err}
+		return nil, &errs.BadRequest{Err: err}
 	}
 	return p, nil
 }